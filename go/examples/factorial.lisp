@@ -0,0 +1,9 @@
+(def factorial
+  (lambda (n)
+    (if (eq? n 0)
+      1
+      (* n (factorial (- n 1))))))
+
+(for-each
+  (lambda (n) (println n "!" "=" (factorial n)))
+  (list 0 1 2 5 10 20))
@@ -0,0 +1,14 @@
+(def numbers (list 3 1 4 1 5 9 2 6 5 3))
+
+(def small? (lambda (n) (<= n 3)))
+
+(println "numbers:" numbers)
+(println "small:" (filter small? numbers))
+(println "doubled:" (map (lambda (n) (+ n n)) numbers))
+(println "sum:" (fold + 0 numbers))
+(println "reversed:" (reverse numbers))
+(println "sorted:" (sort-by (lambda (n) n) numbers))
+(println "largest:" (max 3 1 4 1 5 9 2 6 5 3))
+(println "last:" (last numbers))
+
+(list "done" (iota 3))
@@ -1,9 +1,29 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"hash"
+	"hash/fnv"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"math/rand"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
 )
 
 type SexpKind uint
@@ -11,6 +31,7 @@ type SexpKind uint
 const (
 	Atom SexpKind = iota
 	Pair
+	Literal
 )
 
 type Sexp struct {
@@ -20,6 +41,7 @@ type Sexp struct {
 		car Sexp
 		cdr *Sexp
 	}
+	value interface{}
 }
 
 func (s Sexp) pretty() string {
@@ -27,6 +49,14 @@ func (s Sexp) pretty() string {
 		return s.atom.value
 	}
 
+	if s.kind == Literal {
+		return pretty(s.value)
+	}
+
+	if s.pair == nil {
+		return "NIL"
+	}
+
 	if s.pair.cdr == nil {
 		return fmt.Sprintf("(%s . NIL)", s.pair.car.pretty())
 	}
@@ -36,237 +66,4328 @@ func (s Sexp) pretty() string {
 
 func sexpAppend(first *Sexp, second *Sexp) Sexp {
 	if first == nil {
-		return Sexp{Pair, nil, &struct{car Sexp; cdr *Sexp}{*second, nil}}
+		return Sexp{Pair, nil, &struct{car Sexp; cdr *Sexp}{*second, nil}, nil}
 	}
 
 	if first.kind == Atom {
-		return Sexp{Pair, nil, &struct{car Sexp; cdr *Sexp}{*first, second}}
+		return Sexp{Pair, nil, &struct{car Sexp; cdr *Sexp}{*first, second}, nil}
 	}
 
 	appended := sexpAppend(first.pair.cdr, second)
-	return Sexp{Pair, nil, &struct{car Sexp; cdr *Sexp}{first.pair.car, &appended}}
+	return Sexp{Pair, nil, &struct{car Sexp; cdr *Sexp}{first.pair.car, &appended}, nil}
 }
 
-type TokenKind uint
+type Symbol string
 
-const (
-	Integer TokenKind = iota
-	Identifier
-	Syntax
-)
+type Char rune
 
-type Token struct {
-	value string
-	kind  TokenKind
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+var charNames = map[string]rune{"space": ' ', "newline": '\n', "tab": '\t'}
+
+var fullCaseFolds = map[rune]string{'ß': "ss", 'ẞ': "ss", 'ﬀ': "ff", 'ﬁ': "fi", 'ﬂ': "fl"}
+
+func foldRune(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
 }
 
-func lexInteger(program string, cursor int) (int, Token) {
-	c := program[cursor]
-	end := cursor
-	for c >= '0' && c <= '9' {
-		end++
-		c = program[end]
+type Cons struct {
+	car interface{}
+	cdr interface{}
+}
+
+func listToSlice(list interface{}) []interface{} {
+	var items []interface{}
+	for list != nil {
+		cell := list.(*Cons)
+		items = append(items, cell.car)
+		list = cell.cdr
 	}
+	return items
+}
 
-	return end, Token{program[cursor:end], Integer}
+func sliceToList(items []interface{}) interface{} {
+	var list interface{}
+	for i := len(items) - 1; i >= 0; i-- {
+		list = &Cons{items[i], list}
+	}
+	return list
 }
 
-func lexIdentifier(program string, cursor int) (int, Token) {
-	c := program[cursor]
-	end := cursor
-	for (c >= 'a' && c <= 'z') ||
-		(c >= 'A' && c <= 'Z') ||
-		(c == '+' || c == '-' || c == '*' || c == '&' || c == '$' || c == '%' || c == '<' || c == '=') ||
-		(end > cursor && c >= '0' && c <= '9') {
-		end++
-		c = program[end]
+func lastPair(list interface{}) *Cons {
+	cell, ok := list.(*Cons)
+	if !ok {
+		panic(&LispError{"Expected non-empty list, got", []interface{}{list}})
+	}
+	for {
+		next, ok := cell.cdr.(*Cons)
+		if !ok {
+			return cell
+		}
+		cell = next
 	}
+}
 
-	return end, Token{program[cursor:end], Identifier}
+func copyValue(value interface{}) interface{} {
+	return copyShared(value, map[interface{}]interface{}{})
 }
 
-func lex(program string) []Token {
-	var tokens []Token
-outer:
-	for i := 0; i < len(program); i++ {
-		c := program[i]
-		if c == ' ' || c == '\n' || c == '\t' || c == '\r' {
-			continue
+// copies maps each pair, vector and hash table already visited to its copy,
+// so shared and circular structure is copied once and stays shared.
+func copyShared(value interface{}, copies map[interface{}]interface{}) interface{} {
+	if copied, ok := copies[value]; ok {
+		return copied
+	}
+
+	switch v := value.(type) {
+	case *Cons:
+		head := &Cons{}
+		copies[v] = head
+		head.car = copyShared(v.car, copies)
+		tail := head
+		rest := v.cdr
+		for {
+			cell, ok := rest.(*Cons)
+			if !ok {
+				tail.cdr = copyShared(rest, copies)
+				return head
+			}
+			if copied, ok := copies[cell]; ok {
+				tail.cdr = copied
+				return head
+			}
+			next := &Cons{}
+			copies[cell] = next
+			next.car = copyShared(cell.car, copies)
+			tail.cdr = next
+			tail = next
+			rest = cell.cdr
+		}
+	case *Vector:
+		items := make([]interface{}, len(v.items))
+		vector := &Vector{items}
+		copies[v] = vector
+		for i, item := range v.items {
+			items[i] = copyShared(item, copies)
+		}
+		return vector
+	case *Bytevector:
+		return &Bytevector{append([]byte{}, v.bytes...)}
+	case *HashTable:
+		table := &HashTable{map[interface{}]interface{}{}, v.ordered, nil}
+		copies[v] = table
+		for _, key := range v.keys() {
+			table.set(key, copyShared(v.items[key], copies))
 		}
+		return table
+	}
+	return value
+}
 
-		if c == ')' || c == '(' {
-			tokens = append(tokens, Token{string(c), Syntax})
-			continue
+func isProcedure(value interface{}) bool {
+	switch value.(type) {
+	case *Lambda, *Generator, func(Sexp, map[string]interface{}) interface{}:
+		return true
+	}
+	return false
+}
+
+// A circular list isn't a proper list. The slow pointer steps once for
+// every two steps of the fast one, so a cycle is caught when they meet.
+func isList(value interface{}) bool {
+	slow := value
+	for fast := value; fast != nil; {
+		for i := 0; i < 2 && fast != nil; i++ {
+			cell, ok := fast.(*Cons)
+			if !ok {
+				return false
+			}
+			fast = cell.cdr
 		}
+		slow = slow.(*Cons).cdr
+		if fast != nil && fast == slow {
+			return false
+		}
+	}
+	return true
+}
 
-		lexers := []func(string, int)(int, Token){lexInteger, lexIdentifier}
-		for _, lexer := range lexers {
-			newCursor, token := lexer(program, i)
-			if newCursor == i {
-				continue
+var typePredicates = map[string]func(interface{}) bool{
+	"integer": func(v interface{}) bool { _, ok := v.(int); return ok },
+	"float":   func(v interface{}) bool { _, ok := v.(float64); return ok },
+	"number": func(v interface{}) bool {
+		switch v.(type) {
+		case int, float64:
+			return true
+		}
+		return false
+	},
+	"string":     func(v interface{}) bool { _, ok := v.(string); return ok },
+	"symbol":     func(v interface{}) bool { _, ok := v.(Symbol); return ok },
+	"char":       func(v interface{}) bool { _, ok := v.(Char); return ok },
+	"boolean":    func(v interface{}) bool { _, ok := v.(bool); return ok },
+	"pair":       func(v interface{}) bool { _, ok := v.(*Cons); return ok },
+	"null":       func(v interface{}) bool { return v == nil },
+	"list":       isList,
+	"vector":     func(v interface{}) bool { _, ok := v.(*Vector); return ok },
+	"bytevector": func(v interface{}) bool { _, ok := v.(*Bytevector); return ok },
+	"hash-table": func(v interface{}) bool { _, ok := v.(*HashTable); return ok },
+	"procedure":  isProcedure,
+}
+
+func eqValues(a interface{}, b interface{}) bool {
+	if _, ok := a.(func(Sexp, map[string]interface{}) interface{}); ok {
+		return false
+	}
+
+	return a == b
+}
+
+func eqvValues(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case int:
+		y, ok := b.(int)
+		return ok && x == y
+	case float64:
+		// Compare bit patterns so 0.0 and -0.0 stay distinct and NaN is eqv?
+		// to itself.
+		y, ok := b.(float64)
+		return ok && math.Float64bits(x) == math.Float64bits(y)
+	case Char:
+		y, ok := b.(Char)
+		return ok && x == y
+	case string:
+		// Strings can't be mutated, so two with the same contents are
+		// indistinguishable.
+		y, ok := b.(string)
+		return ok && x == y
+	}
+	return eqValues(a, b)
+}
+
+func equalValues(a interface{}, b interface{}) bool {
+	return equalShared(a, b, map[[2]interface{}]bool{})
+}
+
+// Pairs of structures already being compared are assumed equal, so
+// comparing circular structures terminates.
+func equalShared(a interface{}, b interface{}, seen map[[2]interface{}]bool) bool {
+	switch x := a.(type) {
+	case *Cons:
+		y, ok := b.(*Cons)
+		if !ok || seen[[2]interface{}{x, y}] {
+			return ok
+		}
+		seen[[2]interface{}{x, y}] = true
+		return equalShared(x.car, y.car, seen) && equalShared(x.cdr, y.cdr, seen)
+	case *Vector:
+		y, ok := b.(*Vector)
+		if !ok || len(x.items) != len(y.items) {
+			return false
+		}
+		if seen[[2]interface{}{x, y}] {
+			return true
+		}
+		seen[[2]interface{}{x, y}] = true
+		for i := range x.items {
+			if !equalShared(x.items[i], y.items[i], seen) {
+				return false
 			}
+		}
+		return true
+	case *Bytevector:
+		y, ok := b.(*Bytevector)
+		return ok && bytes.Equal(x.bytes, y.bytes)
+	case nil, int, float64, bool, string, Symbol, Char, *HashTable:
+		return a == b
+	}
 
-			i = newCursor - 1
-			tokens = append(tokens, token)
-			continue outer
+	return eqValues(a, b)
+}
+
+func hashValue(h hash.Hash64, value interface{}) {
+	hashShared(h, value, map[interface{}]int{})
+}
+
+// A structure reached again while it is still being hashed is written as a
+// back-reference to its depth, so hashing circular structures terminates
+// and two copies of the same cycle hash alike. A cycle that is only equal?
+// to another after unrolling may still hash differently.
+func hashShared(h hash.Hash64, value interface{}, seen map[interface{}]int) {
+	switch v := value.(type) {
+	case nil:
+		h.Write([]byte{0})
+	case int:
+		fmt.Fprintf(h, "i%d;", v)
+	case float64:
+		if v == 0 {
+			// -0.0 is equal? to 0.0, so it must hash the same.
+			v = 0
+		}
+		fmt.Fprintf(h, "f%v;", v)
+	case bool:
+		fmt.Fprintf(h, "b%v;", v)
+	case string:
+		fmt.Fprintf(h, "s%d:%s", len(v), v)
+	case Symbol:
+		fmt.Fprintf(h, "y%d:%s", len(v), v)
+	case Char:
+		fmt.Fprintf(h, "c%d;", v)
+	case *Cons:
+		if n, ok := seen[v]; ok {
+			fmt.Fprintf(h, "#%d;", n)
+			return
 		}
+		seen[v] = len(seen)
+		h.Write([]byte{'('})
+		hashShared(h, v.car, seen)
+		hashShared(h, v.cdr, seen)
+		delete(seen, v)
+	case *Vector:
+		if n, ok := seen[v]; ok {
+			fmt.Fprintf(h, "#%d;", n)
+			return
+		}
+		seen[v] = len(seen)
+		fmt.Fprintf(h, "v%d:", len(v.items))
+		for _, item := range v.items {
+			hashShared(h, item, seen)
+		}
+		delete(seen, v)
+	case *Bytevector:
+		fmt.Fprintf(h, "u%d:", len(v.bytes))
+		h.Write(v.bytes)
+	default:
+		fmt.Fprintf(h, "p%p;", v)
+	}
+}
 
-		panic(fmt.Sprintf("Unknown token near '%s' at index '%d'", program[i:], i))
+type Promise struct {
+	thunk  func() interface{}
+	value  interface{}
+	forced bool
+}
+
+func force(value interface{}) interface{} {
+	promise, ok := value.(*Promise)
+	if !ok {
+		return value
 	}
 
-	return tokens
+	if !promise.forced {
+		promise.value = promise.thunk()
+		promise.forced = true
+		promise.thunk = nil
+	}
+	return promise.value
 }
 
-func parse(tokens []Token, cursor int) (int, Sexp) {
-	var siblings *Sexp = nil
+func streamMap(fn interface{}, stream interface{}, ctx map[string]interface{}) interface{} {
+	if stream == nil {
+		return nil
+	}
 
-	if tokens[cursor].value != "(" {
-		panic("Expected opening parenthesis, got: " + tokens[cursor].value)
+	cell := stream.(*Cons)
+	return &Cons{funcall(fn, []interface{}{cell.car}, ctx), &Promise{thunk: func() interface{} {
+		return streamMap(fn, force(cell.cdr), ctx)
+	}}}
+}
+
+func streamFilter(predicate interface{}, stream interface{}, ctx map[string]interface{}) interface{} {
+	for stream != nil {
+		cell := stream.(*Cons)
+		if truthy(funcall(predicate, []interface{}{cell.car}, ctx)) {
+			return &Cons{cell.car, &Promise{thunk: func() interface{} {
+				return streamFilter(predicate, force(cell.cdr), ctx)
+			}}}
+		}
+		stream = force(cell.cdr)
 	}
+	return nil
+}
 
-	cursor++
+type Generator struct {
+	resume  chan bool
+	results chan generatorResult
+	stack   []Frame
+	done    bool
+}
 
-	for ; cursor < len(tokens); cursor++ {
-		t := tokens[cursor]
-		if t.value == "(" {
-			newCursor, child := parse(tokens, cursor)
-			appended := sexpAppend(siblings, &child)
-			siblings = &appended
-			cursor = newCursor
-			continue
+type generatorResult struct {
+	value interface{}
+	err   interface{}
+	done  bool
+}
+
+var generatorClosed = &struct{}{}
+
+func makeGenerator(fn interface{}, ctx map[string]interface{}) *Generator {
+	resume, results := make(chan bool), make(chan generatorResult, 1)
+	yield := func(args Sexp, ctx map[string]interface{}) interface{} {
+		results <- generatorResult{value: evalLisp(args.pair.car, ctx)}
+		if !<-resume {
+			panic(generatorClosed)
 		}
+		return void
+	}
 
-		if t.value == ")" {
-			return cursor, *siblings
+	go func() {
+		defer func() {
+			r := recover()
+			if r == generatorClosed {
+				r = nil
+			}
+			results <- generatorResult{err: r, done: true}
+		}()
+
+		if <-resume {
+			funcall(fn, []interface{}{yield}, ctx)
 		}
+	}()
 
-		s := Sexp{Atom, &t, nil}
-		appended := sexpAppend(siblings, &s)
-		siblings = &appended
+	g := &Generator{resume: resume, results: results}
+	runtime.SetFinalizer(g, (*Generator).finalize)
+	return g
+}
+
+func (g *Generator) next() interface{} {
+	if g.done {
+		return eof
 	}
 
-	return cursor, *siblings
+	saved, savedDeadline := callStack, deadline
+	callStack = g.stack
+	g.resume <- true
+	result := <-g.results
+	g.stack, callStack, deadline = callStack, saved, savedDeadline
+	if result.done {
+		g.done = true
+		if result.err != nil {
+			panic(result.err)
+		}
+		return eof
+	}
+	return result.value
 }
 
-func evalLispArgs(args Sexp, ctx map[string]interface{}) []interface{} {
-	var evalledArgs []interface{}
-	iter := &args
-	for iter != nil {
-		evalledArgs = append(evalledArgs, evalLisp(iter.pair.car, ctx))
-		iter = iter.pair.cdr
+// close unwinds the generator's goroutine, running any dynamic-wind
+// after-thunks it's inside, and waits for it to finish so that none of its
+// Lisp code runs concurrently with the caller's.
+func (g *Generator) close() {
+	if g.done {
+		return
 	}
-	return evalledArgs
+
+	g.done = true
+	saved, savedDeadline, savedDepth := callStack, deadline, traceDepth
+	callStack = g.stack
+	close(g.resume)
+	for result := <-g.results; !result.done; result = <-g.results {
+	}
+	callStack, deadline, traceDepth = saved, savedDeadline, savedDepth
 }
 
-func evalLisp(ast Sexp, ctx map[string]interface{}) interface{} {
-	if ast.kind == Pair {
-		fn := evalLisp(ast.pair.car, ctx)
-		if fn == nil {
-			panic(fmt.Sprintf(("Unknown func: " + ast.pair.car.pretty())))
+// finalize closes a generator that was abandoned without being exhausted.
+// It runs on the garbage collector's goroutine, so it has to wait its turn
+// for the interpreter like any other caller of Eval.
+func (g *Generator) finalize() {
+	evalMutex.Lock()
+	defer evalMutex.Unlock()
+	g.close()
+}
+
+type Lambda struct {
+	params Sexp
+	body   *Sexp
+}
+
+type TailCall struct {
+	lambda *Lambda
+	args   Sexp
+	ctx    map[string]interface{}
+}
+
+func (l *Lambda) call(callArgs Sexp, callCtx map[string]interface{}) interface{} {
+	for {
+		checkDeadline()
+		evalledCallArgs := evalLispArgs(callArgs, callCtx)
+		childCallCtx := map[string]interface{}{}
+		for key, val := range callCtx {
+			childCallCtx[key] = val
 		}
-		return fn.(func(Sexp, map[string]interface{})interface{})(*ast.pair.cdr, ctx)
-	}
 
-	if ast.atom.kind == Integer {
-		i, _ := strconv.Atoi(ast.atom.value)
-		return i
+		iter := &l.params
+		i := 0
+		for iter != nil && iter.pair != nil {
+			childCallCtx[iter.pair.car.atom.value] = evalledCallArgs[i]
+			i++
+			iter = iter.pair.cdr
+		}
+
+		next, result := evalBody(l.body, childCallCtx)
+		if next != nil {
+			result = evalTail(*next, childCallCtx)
+		}
+		tailCall, ok := result.(*TailCall)
+		if !ok {
+			return result
+		}
+		l, callArgs, callCtx = tailCall.lambda, tailCall.args, tailCall.ctx
 	}
+}
 
-	value, ok := ctx[ast.atom.value]
-	if ok {
-		return value
+func callFunction(fn interface{}, args Sexp, ctx map[string]interface{}) interface{} {
+	if lambda, ok := fn.(*Lambda); ok {
+		return lambda.call(args, ctx)
+	}
+	if generator, ok := fn.(*Generator); ok {
+		return generator.next()
 	}
+	return fn.(func(Sexp, map[string]interface{}) interface{})(args, ctx)
+}
 
-	var builtins = map[string]func(Sexp, map[string]interface{}) interface{}{
-		"<=": func(args Sexp, _ map[string]interface{}) interface{} {
-			evalledArgs := evalLispArgs(args, ctx)
-			return evalledArgs[0].(int) <= evalledArgs[1].(int)
-		},
-		"if": func(args Sexp, _ map[string]interface{}) interface{} {
-			test := evalLisp(args.pair.car, ctx)
-			if test.(bool) {
-				return evalLisp(args.pair.cdr.pair.car, ctx)
-			}
+var tailForms = map[string]bool{"if": true, "begin": true, "cond": true, "when": true, "unless": true, "let": true, "and": true, "or": true, "case": true, "let-syntax": true}
 
-			return evalLisp(args.pair.cdr.pair.cdr.pair.car, ctx)
-		},
-		"def": func(args Sexp, _ map[string]interface{}) interface{} {
-			evalledArg := evalLisp(args.pair.cdr.pair.car, ctx)
-			ctx[args.pair.car.atom.value] = evalledArg
-			return evalledArg
-		},
-		"lambda": func(args Sexp, _ map[string]interface{}) interface{} {
-			params := args.pair.car
-			body := args.pair.cdr
+// Evaluates ast in tail position: special forms are unwound in place and a
+// call to a lambda is handed back as a TailCall for Lambda.call to run.
+func evalTail(ast Sexp, ctx map[string]interface{}) interface{} {
+	for ast.kind == Pair && ast.pair != nil {
+		args := Sexp{Pair, nil, nil, nil}
+		if ast.pair.cdr != nil {
+			args = *ast.pair.cdr
+		}
 
-			return func(callArgs Sexp, callCtx map[string]interface{}) interface{} {
-				evalledCallArgs := evalLispArgs(callArgs, callCtx)
-				childCallCtx := map[string]interface{}{}
-				for key, val := range callCtx {
-					childCallCtx[key] = val
+		car := ast.pair.car
+		if car.kind == Atom && car.atom.kind == Identifier && tailForms[car.atom.value] {
+			if _, ok := ctx[car.atom.value]; !ok {
+				next, nextCtx, result := tailForm(car.atom.value, args, ctx)
+				if next == nil {
+					return result
 				}
+				ast, ctx = *next, nextCtx
+				continue
+			}
+		}
 
-				iter := &params
-				i := 0
-				for iter != nil {
-					childCallCtx[iter.pair.car.atom.value] = evalledCallArgs[i]
-					i++
-					iter = iter.pair.cdr
-				}
+		fn := evalLisp(car, ctx)
+		if lambda, ok := fn.(*Lambda); ok {
+			return &TailCall{lambda, args, ctx}
+		}
+		if macro, ok := fn.(*Macro); ok {
+			ast = expandForm(ast, macro, args)
+			continue
+		}
+		return callForm(ast, fn, args, ctx)
+	}
+	return evalLisp(ast, ctx)
+}
 
-				begin := Sexp{Atom, &Token{"begin", Identifier}, nil}
-				begin = sexpAppend(&begin, body)
-				return evalLisp(begin, childCallCtx)
+func evalBody(body *Sexp, ctx map[string]interface{}) (*Sexp, interface{}) {
+	for iter := body; iter != nil && iter.pair != nil; iter = iter.pair.cdr {
+		if iter.pair.cdr == nil || iter.pair.cdr.pair == nil {
+			return &iter.pair.car, nil
+		}
+		evalLisp(iter.pair.car, ctx)
+	}
+	return nil, nil
+}
+
+// Evaluates everything in a special form except its tail expression, which
+// is returned along with the context to evaluate it in.
+func tailForm(name string, args Sexp, ctx map[string]interface{}) (*Sexp, map[string]interface{}, interface{}) {
+	switch name {
+	case "if":
+		if truthy(evalLisp(args.pair.car, ctx)) {
+			return &args.pair.cdr.pair.car, ctx, nil
+		}
+		if alternative := args.pair.cdr.pair.cdr; alternative != nil {
+			return &alternative.pair.car, ctx, nil
+		}
+		return nil, nil, nil
+	case "begin":
+		next, result := evalBody(&args, ctx)
+		return next, ctx, result
+	case "when", "unless":
+		if truthy(evalLisp(args.pair.car, ctx)) != (name == "when") {
+			return nil, nil, nil
+		}
+		next, result := evalBody(args.pair.cdr, ctx)
+		return next, ctx, result
+	case "cond":
+		for clause := &args; clause != nil && clause.pair != nil; clause = clause.pair.cdr {
+			test := clause.pair.car.pair.car
+			var result interface{} = true
+			if test.kind != Atom || test.atom.value != "else" {
+				result = evalLisp(test, ctx)
 			}
-		},
-		"begin": func(args Sexp, _ map[string]interface{}) interface{} {
-			var res interface{}
-			iter := &args
-			for iter != nil {
-				res = evalLisp(iter.pair.car, ctx)
-				iter = iter.pair.cdr
+			if !truthy(result) {
+				continue
 			}
-
-			return res
-		},
-		"+": func(args Sexp, _ map[string]interface{}) interface{} {
-			res := 0
-			for _, arg := range evalLispArgs(args, ctx) {
-				res += arg.(int)
+			if clause.pair.car.pair.cdr == nil {
+				return nil, nil, result
+			}
+			next, result := evalBody(clause.pair.car.pair.cdr, ctx)
+			return next, ctx, result
+		}
+		return nil, nil, nil
+	case "case":
+		key := evalLisp(args.pair.car, ctx)
+		for clause := args.pair.cdr; clause != nil && clause.pair != nil; clause = clause.pair.cdr {
+			data := clause.pair.car.pair.car
+			matched := data.kind == Atom && data.atom.value == "else"
+			if !matched {
+				for _, datum := range listToSlice(quoteSexp(data)) {
+					matched = matched || eqvValues(datum, key)
+				}
+			}
+			if matched {
+				next, result := evalBody(clause.pair.car.pair.cdr, ctx)
+				return next, ctx, result
+			}
+		}
+		return nil, nil, nil
+	case "let":
+		letCtx := map[string]interface{}{}
+		for key, val := range ctx {
+			letCtx[key] = val
+		}
+		for binding := &args.pair.car; binding != nil && binding.pair != nil; binding = binding.pair.cdr {
+			letCtx[binding.pair.car.pair.car.atom.value] = evalLisp(binding.pair.car.pair.cdr.pair.car, ctx)
+		}
+		checkBody(args.pair.cdr)
+		next, result := evalBody(args.pair.cdr, letCtx)
+		return next, letCtx, result
+	case "let-syntax":
+		syntaxCtx := map[string]interface{}{}
+		for key, val := range ctx {
+			syntaxCtx[key] = val
+		}
+		for binding := &args.pair.car; binding != nil && binding.pair != nil; binding = binding.pair.cdr {
+			definition := binding.pair.car
+			syntaxCtx[definition.pair.car.atom.value] = &Macro{definition.pair.cdr.pair.car, definition.pair.cdr.pair.cdr, syntaxCtx}
+		}
+		next, result := evalBody(args.pair.cdr, syntaxCtx)
+		return next, syntaxCtx, result
+	case "and", "or":
+		var result interface{} = name == "and"
+		for iter := &args; iter != nil && iter.pair != nil; iter = iter.pair.cdr {
+			if iter.pair.cdr == nil || iter.pair.cdr.pair == nil {
+				return &iter.pair.car, ctx, nil
 			}
+			result = evalLisp(iter.pair.car, ctx)
+			if truthy(result) != (name == "and") {
+				return nil, nil, result
+			}
+		}
+		return nil, nil, result
+	}
+	panic("Unknown special form: " + name)
+}
 
-			return res
-		},
-		"-": func(args Sexp, _ map[string]interface{}) interface{} {
-			var evalledArgs = evalLispArgs(args, ctx)
-			var res = evalledArgs[0].(int)
-			var rest = evalledArgs[1:]
-			for _, arg := range rest {
-				res -= arg.(int)
+func evalForm(name string, args Sexp, ctx map[string]interface{}) interface{} {
+	next, nextCtx, result := tailForm(name, args, ctx)
+	if next == nil {
+		return result
+	}
+	return evalLisp(*next, nextCtx)
+}
+
+type Values struct {
+	items []interface{}
+}
+
+type Vector struct {
+	items []interface{}
+}
+
+type Bytevector struct {
+	bytes []byte
+}
+
+func toByte(value interface{}) byte {
+	i, ok := value.(int)
+	if !ok || i < 0 || i > 255 {
+		panic(&LispError{"Expected byte between 0 and 255, got", []interface{}{value}})
+	}
+	return byte(i)
+}
+
+func bytevectorIndex(bytevector *Bytevector, index interface{}) int {
+	i := index.(int)
+	if i < 0 || i >= len(bytevector.bytes) {
+		panic(fmt.Sprintf("Bytevector index out of range: %d", i))
+	}
+	return i
+}
+
+type HashTable struct {
+	items   map[interface{}]interface{}
+	ordered bool
+	order   []interface{}
+}
+
+func (h *HashTable) set(key interface{}, value interface{}) {
+	if _, ok := h.items[key]; !ok && h.ordered {
+		h.order = append(h.order, key)
+	}
+	h.items[key] = value
+}
+
+func (h *HashTable) remove(key interface{}) {
+	if _, ok := h.items[key]; ok && h.ordered {
+		for i, k := range h.order {
+			if k == key {
+				h.order = append(h.order[:i], h.order[i+1:]...)
+				break
 			}
-			return res
-		},
+		}
 	}
+	delete(h.items, key)
+}
 
-	value, ok = builtins[ast.atom.value]
-	if !ok {
-		panic("Undefined value :" + ast.atom.value)
+func (h *HashTable) clear() {
+	h.items = map[interface{}]interface{}{}
+	h.order = nil
+}
+
+func (h *HashTable) keys() []interface{} {
+	if h.ordered {
+		return append([]interface{}{}, h.order...)
 	}
 
-	return value
+	var keys []interface{}
+	for key := range h.items {
+		keys = append(keys, key)
+	}
+	return keys
 }
 
-func main() {
-	program := os.Args[1]
-	tokens := lex(program)
-	begin := Sexp{Atom, &Token{"begin", Identifier}, nil}
-	begin = sexpAppend(&begin, nil)
-	cursor, child := parse(tokens, 0)
-	begin = sexpAppend(&begin, &child)
+func formatFloat(f float64, precision int) string {
+	formatted := strconv.FormatFloat(f, 'g', precision, 64)
+	if !strings.ContainsAny(formatted, ".eIN") {
+		formatted += ".0"
+	}
+	return formatted
+}
+
+type datumLabels struct {
+	marked   map[interface{}]bool
+	assigned map[interface{}]int
+}
+
+// findDatumLabels marks the pairs and vectors that need a #n= label to print
+// finitely: those reached again while still inside themselves, and with
+// shared also those reached twice from anywhere. It returns nil when nothing
+// needs a label.
+func findDatumLabels(value interface{}, shared bool) *datumLabels {
+	switch value.(type) {
+	case *Cons, *Vector, *Values:
+	default:
+		return nil
+	}
+
+	visited, inside, marked := map[interface{}]bool{}, map[interface{}]bool{}, map[interface{}]bool{}
+	var walk func(value interface{})
+	seen := func(value interface{}) bool {
+		if inside[value] || (shared && visited[value]) {
+			marked[value] = true
+		}
+		return visited[value]
+	}
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case *Cons:
+			var cells []*Cons
+			var list interface{} = v
+			for {
+				cell, ok := list.(*Cons)
+				if !ok {
+					walk(list)
+					break
+				}
+				if seen(cell) {
+					break
+				}
+				visited[cell], inside[cell] = true, true
+				cells = append(cells, cell)
+				walk(cell.car)
+				list = cell.cdr
+			}
+			for _, cell := range cells {
+				delete(inside, cell)
+			}
+		case *Vector:
+			if seen(v) {
+				return
+			}
+			visited[v], inside[v] = true, true
+			for _, item := range v.items {
+				walk(item)
+			}
+			delete(inside, v)
+		case *Values:
+			for _, item := range v.items {
+				walk(item)
+			}
+		}
+	}
+	walk(value)
+
+	if len(marked) == 0 {
+		return nil
+	}
+	return &datumLabels{marked, map[interface{}]int{}}
+}
+
+// label returns the prefix to print before value and whether value was
+// already printed, in which case the prefix is the whole reference.
+func (l *datumLabels) label(value interface{}) (string, bool) {
+	if l == nil || !l.marked[value] {
+		return "", false
+	}
+	if n, ok := l.assigned[value]; ok {
+		return fmt.Sprintf("#%d#", n), true
+	}
+	l.assigned[value] = len(l.assigned)
+	return fmt.Sprintf("#%d=", l.assigned[value]), false
+}
+
+func pretty(value interface{}) string {
+	return prettyLabelled(value, findDatumLabels(value, false))
+}
+
+func prettyLabelled(value interface{}, labels *datumLabels) string {
+	switch v := value.(type) {
+	case nil:
+		return "NIL"
+	case float64:
+		return formatFloat(v, floatPrecision)
+	case string:
+		return strconv.Quote(v)
+	case Char:
+		for name, c := range charNames {
+			if rune(v) == c {
+				return "#\\" + name
+			}
+		}
+		return "#\\" + string(v)
+	case *Cons:
+		prefix, printed := labels.label(v)
+		if printed {
+			return prefix
+		}
+		items := []string{prettyLabelled(v.car, labels)}
+		list := v.cdr
+		for list != nil {
+			cell, ok := list.(*Cons)
+			if !ok || (labels != nil && labels.marked[cell]) {
+				items = append(items, ".", prettyLabelled(list, labels))
+				break
+			}
+			items = append(items, prettyLabelled(cell.car, labels))
+			list = cell.cdr
+		}
+		return prefix + "(" + strings.Join(items, " ") + ")"
+	case *Vector:
+		prefix, printed := labels.label(v)
+		if printed {
+			return prefix
+		}
+		var items []string
+		for _, item := range v.items {
+			items = append(items, prettyLabelled(item, labels))
+		}
+		return prefix + "#(" + strings.Join(items, " ") + ")"
+	case *Bytevector:
+		var items []string
+		for _, b := range v.bytes {
+			items = append(items, strconv.Itoa(int(b)))
+		}
+		return "#u8(" + strings.Join(items, " ") + ")"
+	case *Values:
+		var items []string
+		for _, item := range v.items {
+			items = append(items, prettyLabelled(item, labels))
+		}
+		return strings.Join(items, " ")
+	case *Promise:
+		return "#<promise>"
+	case *Generator:
+		return "#<generator>"
+	case *InputPort:
+		return "#<input-port>"
+	case *OutputPort:
+		return "#<output-port>"
+	case *EOF:
+		return "#<eof>"
+	case *Lambda:
+		return "#<procedure>"
+	case func(Sexp, map[string]interface{}) interface{}:
+		return "#<builtin>"
+	case *Macro:
+		return "#<macro>"
+	case *Environment:
+		return "#<environment>"
+	case *Void:
+		return "#<void>"
+	case *LispError:
+		return "#<error " + strconv.Quote(v.Error()) + ">"
+	case *HashTable:
+		return fmt.Sprintf("#<hash-table %d>", len(v.items))
+	}
+
+	return fmt.Sprint(value)
+}
+
+// Lays value out within width columns, breaking a list onto one line per
+// item when it doesn't fit. trailing counts the closing parens that will
+// follow value on its last line.
+func prettyLayout(value interface{}, indent int, trailing int, width int) string {
+	flat := pretty(value)
+	cell, ok := value.(*Cons)
+	if !ok || indent+len(flat)+trailing <= width || findDatumLabels(value, false) != nil {
+		return flat
+	}
+
+	var items []string
+	for list := interface{}(cell); list != nil; list = list.(*Cons).cdr {
+		next, ok := list.(*Cons)
+		if !ok {
+			return flat
+		}
+		closing := 0
+		if next.cdr == nil {
+			closing = trailing + 1
+		}
+		items = append(items, prettyLayout(next.car, indent+1, closing, width))
+	}
+	return "(" + strings.Join(items, "\n"+strings.Repeat(" ", indent+1)) + ")"
+}
+
+func display(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	if c, ok := value.(Char); ok {
+		return string(c)
+	}
+
+	if err, ok := value.(*LispError); ok {
+		return err.Error()
+	}
+
+	return pretty(value)
+}
+
+func format(template string, args []interface{}) string {
+	var out strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '~' || i+1 == len(template) {
+			out.WriteByte(template[i])
+			continue
+		}
+
+		i++
+		width, precision := "", ""
+		for ; i < len(template) && template[i] >= '0' && template[i] <= '9'; i++ {
+			width += string(template[i])
+		}
+		if i < len(template) && template[i] == ',' {
+			for i++; i < len(template) && template[i] >= '0' && template[i] <= '9'; i++ {
+				precision += string(template[i])
+			}
+		}
+		if i == len(template) {
+			panic("Incomplete format directive in: " + template)
+		}
+
+		directive := template[i]
+		if directive == '%' {
+			out.WriteByte('\n')
+			continue
+		}
+		if directive == '~' {
+			out.WriteByte('~')
+			continue
+		}
+
+		if len(args) == 0 {
+			panic(fmt.Sprintf("Not enough arguments for format directive ~%c", directive))
+		}
+		arg := args[0]
+		args = args[1:]
+
+		switch directive {
+		case 'a':
+			out.WriteString(fmt.Sprintf("%-"+width+"s", display(arg)))
+		case 's':
+			out.WriteString(fmt.Sprintf("%-"+width+"s", pretty(arg)))
+		case 'd':
+			out.WriteString(fmt.Sprintf("%"+width+"d", arg.(int)))
+		case 'f':
+			f, ok := arg.(float64)
+			if !ok {
+				f = float64(arg.(int))
+			}
+			if precision == "" {
+				out.WriteString(fmt.Sprintf("%"+width+"s", pretty(f)))
+			} else {
+				out.WriteString(fmt.Sprintf("%"+width+"."+precision+"f", f))
+			}
+		default:
+			panic(fmt.Sprintf("Unknown format directive ~%c", directive))
+		}
+	}
+
+	return out.String()
+}
+
+var output io.Writer = os.Stdout
+
+type InputPort struct {
+	reader      *bufio.Reader
+	interactive bool
+}
+
+// Only buffered input counts as ready on an interactive port, so on a
+// line-buffered terminal nothing is ready until the user presses return.
+func (p *InputPort) ready() bool {
+	return !p.interactive || p.reader.Buffered() > 0
+}
+
+type OutputPort struct {
+	writer io.Writer
+}
+
+func outputPort(args []interface{}) io.Writer {
+	if len(args) > 0 {
+		return args[0].(*OutputPort).writer
+	}
+	return output
+}
+
+type EOF struct{}
+
+var eof = &EOF{}
+
+type Void struct{}
+
+var void = &Void{}
+
+var input = &InputPort{bufio.NewReader(os.Stdin), true}
+
+func inputPort(args []interface{}) *InputPort {
+	if len(args) > 0 {
+		return args[0].(*InputPort)
+	}
+	return input
+}
+
+var foldCase = false
+
+var assertionsEnabled = true
+
+var debug = false
+
+var strict = false
+
+var floatPrecision = -1
+
+var source string
+
+var traced = map[string]interface{}{}
+
+var traceDepth = 0
+
+var commandLineArgs []string
+
+var testPasses, testFailures = 0, 0
+
+var gensymCounter = 0
+
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+type TokenKind uint
+
+const (
+	Integer TokenKind = iota
+	Float
+	Character
+	Boolean
+	Identifier
+	String
+	Syntax
+)
+
+type Token struct {
+	value    string
+	kind     TokenKind
+	position int
+}
+
+func lexInteger(program string, cursor int) (int, Token) {
+	c := program[cursor]
+	end := cursor
+	for c >= '0' && c <= '9' {
+		end++
+		c = program[end]
+	}
+
+	return end, Token{program[cursor:end], Integer, cursor}
+}
+
+func lexFloat(program string, cursor int) (int, Token) {
+	end, _ := lexInteger(program, cursor)
+	if end == cursor || program[end] != '.' {
+		return cursor, Token{}
+	}
+
+	fractionEnd, _ := lexInteger(program, end+1)
+	if fractionEnd == end+1 {
+		return cursor, Token{}
+	}
+
+	return fractionEnd, Token{program[cursor:fractionEnd], Float, cursor}
+}
+
+func lexIdentifier(program string, cursor int) (int, Token) {
+	c := program[cursor]
+	end := cursor
+	for (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c == '+' || c == '-' || c == '*' || c == '&' || c == '$' || c == '%' || c == '<' || c == '>' || c == '=' || c == '!' || c == '?' || c == '/') ||
+		(end > cursor && c >= '0' && c <= '9') {
+		end++
+		c = program[end]
+	}
+
+	return end, Token{program[cursor:end], Identifier, cursor}
+}
+
+func lexString(program string, cursor int) (int, Token) {
+	if program[cursor] != '"' {
+		return cursor, Token{}
+	}
+
+	var value strings.Builder
+	end := cursor + 1
+	for end < len(program) && program[end] != '"' {
+		c := program[end]
+		if c == '\\' && end+1 < len(program) {
+			end++
+			c = program[end]
+			if c == 'n' {
+				c = '\n'
+			} else if c == 't' {
+				c = '\t'
+			} else if c == 'r' {
+				c = '\r'
+			}
+		}
+		value.WriteByte(c)
+		end++
+	}
+
+	if end == len(program) {
+		panic(fmt.Sprintf("Unterminated string starting at index '%d'", cursor))
+	}
+
+	return end + 1, Token{value.String(), String, cursor}
+}
+
+func lexChar(program string, cursor int) (int, Token) {
+	if !strings.HasPrefix(program[cursor:], "#\\") || cursor+2 == len(program) {
+		return cursor, Token{}
+	}
+
+	end := cursor + 2
+	for end < len(program) && ((program[end] >= 'a' && program[end] <= 'z') || (program[end] >= 'A' && program[end] <= 'Z')) {
+		end++
+	}
+
+	name := program[cursor+2 : end]
+	if len(name) > 1 {
+		if _, ok := charNames[name]; !ok {
+			panic(fmt.Sprintf("Unknown character name '%s' at index '%d'", name, cursor))
+		}
+		return end, Token{name, Character, cursor}
+	}
+
+	_, size := utf8.DecodeRuneInString(program[cursor+2:])
+	return cursor + 2 + size, Token{program[cursor+2 : cursor+2+size], Character, cursor}
+}
+
+func lexBoolean(program string, cursor int) (int, Token) {
+	for _, literal := range []string{"#true", "#false", "#t", "#f"} {
+		end := cursor + len(literal)
+		if strings.HasPrefix(program[cursor:], literal) && (end == len(program) || strings.IndexByte(" \n\t\r()", program[end]) != -1) {
+			return end, Token{literal, Boolean, cursor}
+		}
+	}
+
+	return cursor, Token{}
+}
+
+func lex(program string) []Token {
+	var tokens []Token
+	start := 0
+	if strings.HasPrefix(program, "#!") {
+		start = strings.IndexByte(program, '\n')
+		if start == -1 {
+			start = len(program)
+		}
+	}
+
+outer:
+	for i := start; i < len(program); i++ {
+		c := program[i]
+		if c == ' ' || c == '\n' || c == '\t' || c == '\r' {
+			continue
+		}
+
+		if c == ')' || c == '(' || c == '\'' {
+			tokens = append(tokens, Token{string(c), Syntax, i})
+			continue
+		}
+
+		if strings.HasPrefix(program[i:], "#u8(") {
+			tokens = append(tokens, Token{"#u8(", Syntax, i})
+			i += 3
+			continue
+		}
+
+		if strings.HasPrefix(program[i:], "#(") {
+			tokens = append(tokens, Token{"#(", Syntax, i})
+			i++
+			continue
+		}
+
+		if c == '.' && (i+1 == len(program) || strings.IndexByte(" \n\t\r()", program[i+1]) != -1) {
+			tokens = append(tokens, Token{".", Syntax, i})
+			continue
+		}
+
+		if label := datumLabel.FindString(program[i:]); label != "" {
+			tokens = append(tokens, Token{label, Syntax, i})
+			i += len(label) - 1
+			continue
+		}
+
+		lexers := []func(string, int)(int, Token){lexFloat, lexInteger, lexIdentifier, lexString, lexChar, lexBoolean}
+		for _, lexer := range lexers {
+			newCursor, token := lexer(program, i)
+			if newCursor == i {
+				continue
+			}
+
+			i = newCursor - 1
+			if foldCase && token.kind == Identifier {
+				token.value = strings.ToLower(token.value)
+			}
+			tokens = append(tokens, token)
+			continue outer
+		}
+
+		panic(fmt.Sprintf("Unknown token near '%s' at index '%d'", program[i:], i))
+	}
+
+	return tokens
+}
+
+var datumLabel = regexp.MustCompile(`^#[0-9]+[=#]`)
+
+type datumPlaceholder struct{}
+
+// replacePlaceholder swaps placeholder for target throughout value, which is
+// how a label's own datum comes to refer back to itself.
+func replacePlaceholder(value interface{}, placeholder *datumPlaceholder, target interface{}, visited map[interface{}]bool) {
+	if visited[value] {
+		return
+	}
+
+	switch v := value.(type) {
+	case *Cons:
+		visited[v] = true
+		if v.car == placeholder {
+			v.car = target
+		} else {
+			replacePlaceholder(v.car, placeholder, target, visited)
+		}
+		if v.cdr == placeholder {
+			v.cdr = target
+		} else {
+			replacePlaceholder(v.cdr, placeholder, target, visited)
+		}
+	case *Vector:
+		visited[v] = true
+		for i, item := range v.items {
+			if item == placeholder {
+				v.items[i] = target
+			} else {
+				replacePlaceholder(item, placeholder, target, visited)
+			}
+		}
+	}
+}
+
+// readDatum reads the datum at cursor as data rather than code, resolving
+// #n= and #n# datum labels against labels.
+func readDatum(tokens []Token, cursor int, labels map[string]interface{}) (int, interface{}) {
+	if cursor == len(tokens) {
+		panic("Unexpected end of input while reading datum")
+	}
+
+	t := tokens[cursor]
+	if t.kind != Syntax {
+		return cursor, quoteSexp(Sexp{Atom, &tokens[cursor], nil, nil})
+	}
+
+	switch {
+	case t.value == "(":
+		var items []interface{}
+		var tail interface{}
+		for cursor++; cursor < len(tokens) && tokens[cursor].value != ")"; cursor++ {
+			if tokens[cursor].value == "." && len(items) > 0 {
+				cursor, tail = readDatum(tokens, cursor+1, labels)
+				if cursor+1 == len(tokens) || tokens[cursor+1].value != ")" {
+					panic(fmt.Sprintf("Expected closing parenthesis after dotted tail at index '%d'", t.position))
+				}
+				cursor++
+				break
+			}
+
+			var item interface{}
+			cursor, item = readDatum(tokens, cursor, labels)
+			items = append(items, item)
+		}
+		if cursor == len(tokens) {
+			panic(fmt.Sprintf("Unterminated list starting at index '%d'", t.position))
+		}
+
+		for i := len(items) - 1; i >= 0; i-- {
+			tail = &Cons{items[i], tail}
+		}
+		return cursor, tail
+	case t.value == "#(":
+		vector := &Vector{}
+		for cursor++; cursor < len(tokens) && tokens[cursor].value != ")"; cursor++ {
+			var item interface{}
+			cursor, item = readDatum(tokens, cursor, labels)
+			vector.items = append(vector.items, item)
+		}
+		if cursor == len(tokens) {
+			panic(fmt.Sprintf("Unterminated vector starting at index '%d'", t.position))
+		}
+		return cursor, vector
+	case t.value == "#u8(":
+		cursor, bytevector := parseBytevector(tokens, cursor)
+		return cursor, bytevector.value
+	case t.value == "'":
+		cursor, quoted := readDatum(tokens, cursor+1, labels)
+		return cursor, &Cons{Symbol("quote"), &Cons{quoted, nil}}
+	case strings.HasSuffix(t.value, "="):
+		name := strings.TrimSuffix(t.value[1:], "=")
+		placeholder := &datumPlaceholder{}
+		labels[name] = placeholder
+		cursor, value := readDatum(tokens, cursor+1, labels)
+		if value == placeholder {
+			panic(fmt.Sprintf("Datum label #%s= refers only to itself at index '%d'", name, t.position))
+		}
+		replacePlaceholder(value, placeholder, value, map[interface{}]bool{})
+		labels[name] = value
+		return cursor, value
+	case strings.HasSuffix(t.value, "#"):
+		name := strings.TrimSuffix(t.value[1:], "#")
+		value, ok := labels[name]
+		if !ok {
+			panic(fmt.Sprintf("Undefined datum label #%s# at index '%d'", name, t.position))
+		}
+		return cursor, value
+	}
+
+	panic(fmt.Sprintf("Unexpected '%s' at index '%d'", t.value, t.position))
+}
+
+func parseBytevector(tokens []Token, cursor int) (int, Sexp) {
+	var items []byte
+	for cursor++; cursor < len(tokens) && tokens[cursor].value != ")"; cursor++ {
+		t := tokens[cursor]
+		i, err := strconv.Atoi(t.value)
+		if t.kind != Integer || err != nil || i > 255 {
+			panic(fmt.Sprintf("Invalid bytevector element '%s' at index '%d'", t.value, t.position))
+		}
+		items = append(items, byte(i))
+	}
+
+	if cursor == len(tokens) {
+		panic("Unterminated bytevector literal")
+	}
+
+	return cursor, Sexp{Literal, nil, nil, &Bytevector{items}}
+}
+
+func parse(tokens []Token, cursor int, labels map[string]interface{}) (int, Sexp) {
+	var siblings *Sexp = nil
+
+	if tokens[cursor].value != "(" {
+		panic("Expected opening parenthesis, got: " + tokens[cursor].value)
+	}
+
+	cursor++
+
+	for ; cursor < len(tokens); cursor++ {
+		t := tokens[cursor]
+		if t.kind == Syntax && t.value == "(" {
+			newCursor, child := parse(tokens, cursor, labels)
+			appended := sexpAppend(siblings, &child)
+			siblings = &appended
+			cursor = newCursor
+			continue
+		}
+
+		if t.kind == Syntax && t.value == "#u8(" {
+			newCursor, child := parseBytevector(tokens, cursor)
+			appended := sexpAppend(siblings, &child)
+			siblings = &appended
+			cursor = newCursor
+			continue
+		}
+
+		if t.kind == Syntax && (t.value == "#(" || datumLabel.MatchString(t.value)) {
+			newCursor, value := readDatum(tokens, cursor, labels)
+			child := Sexp{Literal, nil, nil, value}
+			appended := sexpAppend(siblings, &child)
+			siblings = &appended
+			cursor = newCursor
+			continue
+		}
+
+		if t.kind == Syntax && t.value == "'" {
+			var datum interface{}
+			cursor, datum = readDatum(tokens, cursor+1, labels)
+			quoted := Sexp{Literal, nil, nil, datum}
+
+			quote := Sexp{Atom, &Token{"quote", Identifier, t.position}, nil, nil}
+			quote = sexpAppend(&quote, nil)
+			quote = sexpAppend(&quote, &quoted)
+			appended := sexpAppend(siblings, &quote)
+			siblings = &appended
+			continue
+		}
+
+		if t.kind == Syntax && t.value == ")" {
+			if siblings == nil {
+				return cursor, Sexp{Pair, nil, nil, nil}
+			}
+
+			return cursor, *siblings
+		}
+
+		s := Sexp{Atom, &t, nil, nil}
+		appended := sexpAppend(siblings, &s)
+		siblings = &appended
+	}
+
+	return cursor, *siblings
+}
+
+type Frame struct {
+	name     string
+	position int
+}
+
+var callStack []Frame
+
+func location(program string, position int) string {
+	before := program[:position]
+	line := strings.Count(before, "\n") + 1
+	column := position - strings.LastIndex(before, "\n")
+	return fmt.Sprintf("line %d, column %d", line, column)
+}
+
+var definitionForms = map[string]bool{"def": true, "define-constant": true, "defmacro": true}
+
+var warnedDefinitions = map[int]bool{}
+
+// Internal definitions have to come before the other forms in a body, as
+// in Scheme. A misplaced one is a warning, or an error with -strict.
+func checkBody(body *Sexp) {
+	seenExpression := false
+	for iter := body; iter != nil && iter.pair != nil; iter = iter.pair.cdr {
+		form := iter.pair.car
+		isDefinition := form.kind == Pair && form.pair != nil && form.pair.car.kind == Atom && definitionForms[form.pair.car.atom.value]
+		if !isDefinition {
+			seenExpression = true
+			continue
+		}
+		if !seenExpression {
+			continue
+		}
+
+		head := form.pair.car.atom
+		message := head.value + " of " + form.pair.cdr.pair.car.pretty() + " follows expressions in body"
+		if head.position >= 0 && head.position <= len(source) {
+			message += " at " + location(source, head.position)
+		}
+		if strict {
+			panic(&LispError{"Misplaced definition: " + message, nil})
+		}
+		if !warnedDefinitions[head.position] {
+			warnedDefinitions[head.position] = true
+			fmt.Fprintln(os.Stderr, "Warning: "+message)
+		}
+	}
+}
+
+func backtrace(program string, frames []Frame) string {
+	var lines []string
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i].position < 0 {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("  %s at %s", frames[i].name, location(program, frames[i].position)))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Backtrace:\n" + strings.Join(lines, "\n")
+}
+
+type LispError struct {
+	message   string
+	irritants []interface{}
+}
+
+func (e *LispError) Error() string {
+	message := e.message
+	for _, irritant := range e.irritants {
+		message += " " + pretty(irritant)
+	}
+	return message
+}
+
+type Raised struct {
+	value interface{}
+}
+
+func (r *Raised) Error() string {
+	return "Uncaught raise: " + pretty(r.value)
+}
+
+func raise(value interface{}) {
+	if err, ok := value.(*LispError); ok {
+		panic(err)
+	}
+	panic(&Raised{value})
+}
+
+type Timeout struct {
+	deadline time.Time
+}
+
+var deadline time.Time
+
+func checkDeadline() {
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		panic(&Timeout{deadline})
+	}
+}
+
+func catchError(thunk func() interface{}) (result interface{}, caught interface{}, failed bool) {
+	depth := len(callStack)
+	defer func() {
+		if r := recover(); r != nil {
+			if timeout, ok := r.(*Timeout); ok {
+				panic(timeout)
+			}
+			if r == generatorClosed {
+				panic(r)
+			}
+
+			callStack = callStack[:depth]
+			failed = true
+			switch err := r.(type) {
+			case *LispError:
+				caught = err
+			case *Raised:
+				caught = err.value
+			default:
+				caught = &LispError{fmt.Sprint(r), nil}
+			}
+		}
+	}()
+
+	return thunk(), nil, false
+}
+
+func coerceNumbers(args []interface{}) bool {
+	for _, arg := range args {
+		if _, ok := arg.(float64); ok {
+			for i, arg := range args {
+				if n, ok := arg.(int); ok {
+					args[i] = float64(n)
+				}
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+func toFloat(value interface{}) float64 {
+	switch n := value.(type) {
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	panic("Expected number: " + pretty(value))
+}
+
+func approxEqual(args []interface{}) bool {
+	tolerance := 1e-9
+	if len(args) > 2 {
+		tolerance = toFloat(args[2])
+	}
+	return math.Abs(toFloat(args[0])-toFloat(args[1])) <= tolerance
+}
+
+func span(predicate interface{}, list interface{}, matching bool, ctx map[string]interface{}) *Values {
+	var prefix []interface{}
+	for list != nil {
+		item := list.(*Cons).car
+		if truthy(funcall(predicate, []interface{}{item}, ctx)) != matching {
+			break
+		}
+		prefix = append(prefix, item)
+		list = list.(*Cons).cdr
+	}
+	return &Values{[]interface{}{sliceToList(prefix), list}}
+}
+
+func parseNumber(s string, radix int) interface{} {
+	if n, err := strconv.ParseInt(s, radix, 0); err == nil {
+		return int(n)
+	}
+	if radix == 10 && !strings.ContainsAny(strings.ToLower(s), "inxp_") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return false
+}
+
+func readJSON(decoder *json.Decoder) interface{} {
+	token, err := decoder.Token()
+	if err != nil {
+		panic(&LispError{"Invalid JSON: " + err.Error(), nil})
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '[' {
+			var items []interface{}
+			for decoder.More() {
+				items = append(items, readJSON(decoder))
+			}
+			decoder.Token()
+			return sliceToList(items)
+		}
+
+		object := &HashTable{map[interface{}]interface{}{}, true, nil}
+		for decoder.More() {
+			key := readJSON(decoder)
+			object.set(key, readJSON(decoder))
+		}
+		decoder.Token()
+		return object
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return int(n)
+		}
+		f, _ := t.Float64()
+		return f
+	}
+	return token
+}
+
+// Only hash tables serialize as objects; every list, alists included,
+// becomes an array and the empty list becomes null.
+func writeJSON(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case *Cons:
+		if !isList(v) {
+			panic(&LispError{"Cannot serialize an improper list to JSON", []interface{}{value}})
+		}
+		var items []string
+		for _, item := range listToSlice(v) {
+			items = append(items, writeJSON(item))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case *Vector:
+		var items []string
+		for _, item := range v.items {
+			items = append(items, writeJSON(item))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case *HashTable:
+		var members []string
+		for _, key := range v.keys() {
+			name, ok := key.(string)
+			if symbol, isSymbol := key.(Symbol); isSymbol {
+				name, ok = string(symbol), true
+			}
+			if !ok {
+				panic(&LispError{"JSON object key must be a string or symbol", []interface{}{key}})
+			}
+			members = append(members, writeJSON(name)+":"+writeJSON(v.items[key]))
+		}
+		return "{" + strings.Join(members, ",") + "}"
+	case Symbol:
+		return writeJSON(string(v))
+	case Char:
+		return writeJSON(string(rune(v)))
+	case string, int, float64, bool:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			panic(&LispError{"Cannot serialize to JSON: " + err.Error(), []interface{}{v}})
+		}
+		if _, isFloat := v.(float64); isFloat && !strings.ContainsAny(string(encoded), ".e") {
+			return string(encoded) + ".0"
+		}
+		return string(encoded)
+	}
+	panic(&LispError{"Cannot serialize to JSON", []interface{}{value}})
+}
+
+func readFile(path string) string {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		panic(&LispError{"Cannot read file: " + err.Error(), []interface{}{path}})
+	}
+	return string(contents)
+}
+
+func shrinkCandidates(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case int:
+		if v == 0 {
+			return nil
+		}
+		candidates := []interface{}{0}
+		if v/2 != 0 {
+			candidates = append(candidates, v/2)
+		}
+		if v > 0 {
+			return append(candidates, v-1)
+		}
+		return append(candidates, v+1)
+	case *Cons:
+		items := listToSlice(v)
+		var candidates []interface{}
+		for i := range items {
+			without := append(append([]interface{}{}, items[:i]...), items[i+1:]...)
+			candidates = append(candidates, sliceToList(without))
+		}
+		for i, item := range items {
+			for _, smaller := range shrinkCandidates(item) {
+				replaced := append([]interface{}{}, items...)
+				replaced[i] = smaller
+				candidates = append(candidates, sliceToList(replaced))
+			}
+		}
+		return candidates
+	}
+	return nil
+}
+
+func compileRegex(pattern string) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(&LispError{"Invalid regex: " + err.Error(), []interface{}{pattern}})
+	}
+	return re
+}
+
+func truthy(value interface{}) bool {
+	return value != false && value != nil
+}
+
+func evalCond(clauses *Sexp, ctx map[string]interface{}) (interface{}, bool) {
+	for clause := clauses; clause != nil && clause.pair != nil; clause = clause.pair.cdr {
+		test := clause.pair.car.pair.car
+		var result interface{} = true
+		if test.kind != Atom || test.atom.value != "else" {
+			result = evalLisp(test, ctx)
+		}
+		if !truthy(result) {
+			continue
+		}
+
+		for body := clause.pair.car.pair.cdr; body != nil; body = body.pair.cdr {
+			result = evalLisp(body.pair.car, ctx)
+		}
+		return result, true
+	}
+
+	return nil, false
+}
+
+func copyContext(ctx map[string]interface{}) map[string]interface{} {
+	child := map[string]interface{}{}
+	for key, val := range ctx {
+		child[key] = val
+	}
+	return child
+}
+
+func errorObject(name string, value interface{}) *LispError {
+	err, ok := value.(*LispError)
+	if !ok {
+		panic(&LispError{fmt.Sprintf("%s: expected error object, got %s %s", name, typeName(value), pretty(value)), nil})
+	}
+	return err
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case int:
+		return "integer"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case Symbol:
+		return "symbol"
+	case Char:
+		return "char"
+	case bool:
+		return "boolean"
+	case *Cons:
+		return "pair"
+	case *Vector:
+		return "vector"
+	case *Bytevector:
+		return "bytevector"
+	case *HashTable:
+		return "hash-table"
+	}
+	if isProcedure(value) {
+		return "procedure"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func checkNumbers(name string, args []interface{}, integersOnly bool) {
+	for i, arg := range args {
+		switch arg.(type) {
+		case int:
+			continue
+		case float64:
+			if !integersOnly {
+				continue
+			}
+		}
+
+		expected := "number"
+		if integersOnly {
+			expected = "integer"
+		}
+		panic(&LispError{fmt.Sprintf("%s: expected %s in position %d, got %s %s", name, expected, i+1, typeName(arg), pretty(arg)), nil})
+	}
+}
+
+func lessThan(a interface{}, b interface{}) bool {
+	args := []interface{}{a, b}
+	if coerceNumbers(args) {
+		return args[0].(float64) < args[1].(float64)
+	}
+	return args[0].(int) < args[1].(int)
+}
+
+func extremum(name string, items []interface{}, key interface{}, wantMax bool, ctx map[string]interface{}) interface{} {
+	if len(items) == 0 {
+		panic(name + ": expected at least one value")
+	}
+
+	best, bestKey := items[0], items[0]
+	if key != nil {
+		bestKey = funcall(key, []interface{}{best}, ctx)
+	}
+	for _, item := range items[1:] {
+		itemKey := item
+		if key != nil {
+			itemKey = funcall(key, []interface{}{item}, ctx)
+		}
+		better := lessThan(itemKey, bestKey)
+		if wantMax {
+			better = lessThan(bestKey, itemKey)
+		}
+		if better {
+			best, bestKey = item, itemKey
+		}
+	}
+	return best
+}
+
+func evalLispArgs(args Sexp, ctx map[string]interface{}) []interface{} {
+	var evalledArgs []interface{}
+	iter := &args
+	for iter != nil && iter.pair != nil {
+		evalledArgs = append(evalledArgs, evalLisp(iter.pair.car, ctx))
+		iter = iter.pair.cdr
+	}
+	return evalledArgs
+}
+
+func unquoteSexp(value interface{}) Sexp {
+	switch v := value.(type) {
+	case Symbol:
+		return Sexp{Atom, &Token{string(v), Identifier, -1}, nil, nil}
+	case *Cons:
+		var cdr *Sexp
+		if v.cdr != nil {
+			rest := unquoteSexp(v.cdr)
+			if rest.kind != Pair {
+				panic("Cannot evaluate improper list: " + pretty(v))
+			}
+			cdr = &rest
+		}
+		return Sexp{Pair, nil, &struct{car Sexp; cdr *Sexp}{unquoteSexp(v.car), cdr}, nil}
+	}
+	return Sexp{Literal, nil, nil, value}
+}
+
+// Macros expand in the environment they were defined in, so the use site's
+// bindings can't change what the expander sees.
+type Macro struct {
+	params Sexp
+	body   *Sexp
+	ctx    map[string]interface{}
+}
+
+// Expansion isn't hygienic: symbols a macro introduces are resolved and
+// bound at the use site like any others, so temporaries need gensym.
+func expandMacro(macro *Macro, args Sexp) interface{} {
+	macroCtx := map[string]interface{}{}
+	for key, val := range macro.ctx {
+		macroCtx[key] = val
+	}
+
+	arg := &args
+	for param := &macro.params; param != nil && param.pair != nil; param = param.pair.cdr {
+		name := param.pair.car.atom.value
+		if name == "&rest" {
+			var rest interface{}
+			if arg != nil && arg.pair != nil {
+				rest = quoteSexp(*arg)
+			}
+			macroCtx[param.pair.cdr.pair.car.atom.value] = rest
+			break
+		}
+
+		if arg == nil || arg.pair == nil {
+			panic("Too few arguments to macro")
+		}
+		macroCtx[name] = quoteSexp(arg.pair.car)
+		arg = arg.pair.cdr
+	}
+
+	next, result := evalBody(macro.body, macroCtx)
+	if next != nil {
+		result = evalLisp(*next, macroCtx)
+	}
+	return result
+}
+
+func expandForm(ast Sexp, macro *Macro, args Sexp) Sexp {
+	if ast.pair.car.kind != Atom {
+		return unquoteSexp(expandMacro(macro, args))
+	}
+
+	callStack = append(callStack, Frame{ast.pair.car.atom.value, ast.pair.car.atom.position})
+	expansion := expandMacro(macro, args)
+	callStack = callStack[:len(callStack)-1]
+	return unquoteSexp(expansion)
+}
+
+func macroexpand1(form interface{}, ctx map[string]interface{}) (interface{}, bool) {
+	cell, ok := form.(*Cons)
+	if !ok {
+		return form, false
+	}
+
+	name, ok := cell.car.(Symbol)
+	if !ok {
+		return form, false
+	}
+
+	macro, ok := ctx[string(name)].(*Macro)
+	if !ok {
+		return form, false
+	}
+
+	args := Sexp{Pair, nil, nil, nil}
+	if cell.cdr != nil {
+		args = unquoteSexp(cell.cdr)
+	}
+	return expandMacro(macro, args), true
+}
+
+type Environment struct {
+	ctx map[string]interface{}
+}
+
+// A binding made by define-constant. The scope it was defined in can't
+// redefine it, but nested scopes are free to shadow it with def or let.
+type Constant struct {
+	value interface{}
+	scope map[string]interface{}
+}
+
+func isConstantIn(ctx map[string]interface{}, name string) bool {
+	constant, ok := ctx[name].(*Constant)
+	return ok && reflect.ValueOf(constant.scope).Pointer() == reflect.ValueOf(ctx).Pointer()
+}
+
+func quoteSexp(s Sexp) interface{} {
+	if s.kind == Literal {
+		return s.value
+	}
+
+	if s.kind == Atom {
+		switch s.atom.kind {
+		case Integer:
+			i, _ := strconv.Atoi(s.atom.value)
+			return i
+		case Float:
+			f, _ := strconv.ParseFloat(s.atom.value, 64)
+			return f
+		case String:
+			return s.atom.value
+		case Boolean:
+			return s.atom.value == "#t" || s.atom.value == "#true"
+		case Character:
+			if c, ok := charNames[s.atom.value]; ok {
+				return Char(c)
+			}
+			c, _ := utf8.DecodeRuneInString(s.atom.value)
+			return Char(c)
+		}
+		return Symbol(s.atom.value)
+	}
+
+	if s.pair == nil {
+		return nil
+	}
+
+	var cdr interface{}
+	if s.pair.cdr != nil {
+		cdr = quoteSexp(*s.pair.cdr)
+	}
+	return &Cons{quoteSexp(s.pair.car), cdr}
+}
+
+func funcall(fn interface{}, args []interface{}, ctx map[string]interface{}) interface{} {
+	callArgs := &Sexp{Pair, nil, nil, nil}
+	for i := len(args) - 1; i >= 0; i-- {
+		var rest *Sexp
+		if callArgs.pair != nil {
+			rest = callArgs
+		}
+		callArgs = &Sexp{Pair, nil, &struct{car Sexp; cdr *Sexp}{Sexp{Literal, nil, nil, args[i]}, rest}, nil}
+	}
+
+	return callFunction(fn, *callArgs, ctx)
+}
+
+func callForm(ast Sexp, fn interface{}, callArgs Sexp, ctx map[string]interface{}) interface{} {
+	if macro, ok := fn.(*Macro); ok {
+		return evalLisp(expandForm(ast, macro, callArgs), ctx)
+	}
+
+	if ast.pair.car.kind != Atom {
+		return callFunction(fn, callArgs, ctx)
+	}
+
+	if _, ok := ctx[ast.pair.car.atom.value]; !ok {
+		return callFunction(fn, callArgs, ctx)
+	}
+
+	callStack = append(callStack, Frame{ast.pair.car.atom.value, ast.pair.car.atom.position})
+	result := callFunction(fn, callArgs, ctx)
+	callStack = callStack[:len(callStack)-1]
+	return result
+}
+
+func evalLisp(ast Sexp, ctx map[string]interface{}) interface{} {
+	if ast.kind == Literal {
+		return ast.value
+	}
+
+	if ast.kind == Pair {
+		checkDeadline()
+
+		fn := evalLisp(ast.pair.car, ctx)
+		if fn == nil {
+			panic("Unknown func: " + ast.pair.car.pretty())
+		}
+		callArgs := Sexp{Pair, nil, nil, nil}
+		if ast.pair.cdr != nil {
+			callArgs = *ast.pair.cdr
+		}
+		return callForm(ast, fn, callArgs, ctx)
+	}
+
+	if ast.atom.kind == Integer {
+		i, _ := strconv.Atoi(ast.atom.value)
+		return i
+	}
+
+	if ast.atom.kind == Float {
+		f, _ := strconv.ParseFloat(ast.atom.value, 64)
+		return f
+	}
+
+	if ast.atom.kind == String {
+		return ast.atom.value
+	}
+
+	if ast.atom.kind == Character || ast.atom.kind == Boolean {
+		return quoteSexp(ast)
+	}
+
+	value, ok := ctx[ast.atom.value]
+	if ok {
+		if constant, ok := value.(*Constant); ok {
+			return constant.value
+		}
+		return value
+	}
+
+	value, ok = builtins[ast.atom.value]
+	if !ok {
+		panic("Undefined value :" + ast.atom.value)
+	}
+
+	return value
+}
+
+var builtins map[string]func(Sexp, map[string]interface{}) interface{}
+
+func init() {
+	builtins = map[string]func(Sexp, map[string]interface{}) interface{}{
+		"<=": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			checkNumbers("<=", evalledArgs, false)
+			if coerceNumbers(evalledArgs) {
+				return evalledArgs[0].(float64) <= evalledArgs[1].(float64)
+			}
+			return evalledArgs[0].(int) <= evalledArgs[1].(int)
+		},
+		"if": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("if", args, ctx)
+		},
+		"when": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("when", args, ctx)
+		},
+		"unless": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("unless", args, ctx)
+		},
+		"let": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("let", args, ctx)
+		},
+		"and": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("and", args, ctx)
+		},
+		"or": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("or", args, ctx)
+		},
+		"case": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("case", args, ctx)
+		},
+		"let-syntax": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("let-syntax", args, ctx)
+		},
+		"def": func(args Sexp, ctx map[string]interface{}) interface{} {
+			name := args.pair.car.atom.value
+			if isConstantIn(ctx, name) {
+				panic(&LispError{"Cannot redefine constant: " + name, nil})
+			}
+
+			ctx[name] = evalLisp(args.pair.cdr.pair.car, ctx)
+			return void
+		},
+		"define-constant": func(args Sexp, ctx map[string]interface{}) interface{} {
+			name := args.pair.car.atom.value
+			if isConstantIn(ctx, name) {
+				panic(&LispError{"Cannot redefine constant: " + name, nil})
+			}
+
+			ctx[name] = &Constant{evalLisp(args.pair.cdr.pair.car, ctx), ctx}
+			return void
+		},
+		"set!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			name := args.pair.car.atom.value
+			if _, ok := ctx[name].(*Constant); ok {
+				panic(&LispError{"Cannot set constant: " + name, nil})
+			}
+			if _, ok := ctx[name]; !ok {
+				panic("Unbound variable: " + name)
+			}
+
+			ctx[name] = evalLisp(args.pair.cdr.pair.car, ctx)
+			return void
+		},
+		"lambda": func(args Sexp, ctx map[string]interface{}) interface{} {
+			params := args.pair.car
+			body := args.pair.cdr
+			checkBody(body)
+
+			return &Lambda{params, body}
+		},
+		"defmacro": func(args Sexp, ctx map[string]interface{}) interface{} {
+			name := args.pair.car.atom.value
+			if isConstantIn(ctx, name) {
+				panic(&LispError{"Cannot redefine constant: " + name, nil})
+			}
+
+			ctx[name] = &Macro{args.pair.cdr.pair.car, args.pair.cdr.pair.cdr, ctx}
+			return void
+		},
+		"gensym": func(args Sexp, ctx map[string]interface{}) interface{} {
+			gensymCounter++
+			return Symbol(fmt.Sprintf("#:g%d", gensymCounter))
+		},
+		"syntax-error": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			panic(&LispError{"Syntax error: " + evalledArgs[0].(string), evalledArgs[1:]})
+		},
+		"macroexpand-1": func(args Sexp, ctx map[string]interface{}) interface{} {
+			expansion, _ := macroexpand1(evalLisp(args.pair.car, ctx), ctx)
+			return expansion
+		},
+		"macroexpand": func(args Sexp, ctx map[string]interface{}) interface{} {
+			form, expanded := evalLisp(args.pair.car, ctx), true
+			for expanded {
+				form, expanded = macroexpand1(form, ctx)
+			}
+			return form
+		},
+		"disassemble": func(args Sexp, ctx map[string]interface{}) interface{} {
+			lambda, ok := evalLisp(args.pair.car, ctx).(*Lambda)
+			if !ok {
+				fmt.Fprintln(output, "#<builtin>")
+				return void
+			}
+
+			params := "()"
+			if lambda.params.pair != nil {
+				params = pretty(quoteSexp(lambda.params))
+			}
+			fmt.Fprintf(output, "(lambda %s", params)
+			for iter := lambda.body; iter != nil && iter.pair != nil; iter = iter.pair.cdr {
+				fmt.Fprintf(output, "\n  %s", pretty(quoteSexp(iter.pair.car)))
+			}
+			fmt.Fprintln(output, ")")
+			return void
+		},
+		"cond": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("cond", args, ctx)
+		},
+		"delay": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return &Promise{thunk: func() interface{} {
+				return evalLisp(args.pair.car, ctx)
+			}}
+		},
+		"make-generator": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return makeGenerator(evalLisp(args.pair.car, ctx), ctx)
+		},
+		"generator-close": func(args Sexp, ctx map[string]interface{}) interface{} {
+			generator, ok := evalLisp(args.pair.car, ctx).(*Generator)
+			if !ok {
+				panic("generator-close: expected generator")
+			}
+			generator.close()
+			return void
+		},
+		"force": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return force(evalLisp(args.pair.car, ctx))
+		},
+		"cons-stream": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return &Cons{evalLisp(args.pair.car, ctx), &Promise{thunk: func() interface{} {
+				return evalLisp(args.pair.cdr.pair.car, ctx)
+			}}}
+		},
+		"stream-car": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalLisp(args.pair.car, ctx).(*Cons).car
+		},
+		"stream-cdr": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return force(evalLisp(args.pair.car, ctx).(*Cons).cdr)
+		},
+		"stream-take": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			var items []interface{}
+			stream := evalledArgs[0]
+			for i := 0; i < evalledArgs[1].(int) && stream != nil; i++ {
+				items = append(items, stream.(*Cons).car)
+				if i+1 < evalledArgs[1].(int) {
+					stream = force(stream.(*Cons).cdr)
+				}
+			}
+			return sliceToList(items)
+		},
+		"stream-map": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return streamMap(evalledArgs[0], evalledArgs[1], ctx)
+		},
+		"stream-filter": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return streamFilter(evalledArgs[0], evalledArgs[1], ctx)
+		},
+		"random": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			if len(evalledArgs) == 0 {
+				return random.Float64()
+			}
+
+			n, ok := evalledArgs[0].(int)
+			if !ok || n <= 0 {
+				panic("Expected positive integer: " + pretty(evalledArgs[0]))
+			}
+			return random.Intn(n)
+		},
+		"random-seed": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			random.Seed(int64(evalledArgs[0].(int)))
+			return void
+		},
+		"shuffle": func(args Sexp, ctx map[string]interface{}) interface{} {
+			items := listToSlice(evalLispArgs(args, ctx)[0])
+			for i := len(items) - 1; i > 0; i-- {
+				j := random.Intn(i + 1)
+				items[i], items[j] = items[j], items[i]
+			}
+			return sliceToList(items)
+		},
+		"sample": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			items := listToSlice(evalledArgs[0])
+			k := evalledArgs[1].(int)
+			if k < 0 || k > len(items) {
+				panic(fmt.Sprintf("Sample size out of range: %d", k))
+			}
+
+			for i := 0; i < k; i++ {
+				j := i + random.Intn(len(items)-i)
+				items[i], items[j] = items[j], items[i]
+			}
+			return sliceToList(items[:k])
+		},
+		"string->number": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			radix := 10
+			if len(evalledArgs) > 1 {
+				radix = evalledArgs[1].(int)
+			}
+			return parseNumber(evalledArgs[0].(string), radix)
+		},
+		"set-float-precision!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			precision, ok := evalLisp(args.pair.car, ctx).(int)
+			if !ok || precision == 0 || precision < -1 {
+				panic("Expected positive precision or -1")
+			}
+
+			previous := floatPrecision
+			floatPrecision = precision
+			return previous
+		},
+		"number->string": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			radix := 10
+			if len(evalledArgs) > 1 {
+				radix = evalledArgs[1].(int)
+			}
+
+			switch n := evalledArgs[0].(type) {
+			case int:
+				if radix < 2 || radix > 36 {
+					panic(fmt.Sprintf("Radix out of range: %d", radix))
+				}
+				return strconv.FormatInt(int64(n), radix)
+			case float64:
+				if radix != 10 {
+					panic("Inexact numbers can only be written in radix 10")
+				}
+				return formatFloat(n, -1)
+			}
+			panic("Expected number: " + pretty(evalledArgs[0]))
+		},
+		"string-number?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return parseNumber(evalLispArgs(args, ctx)[0].(string), 10) != false
+		},
+		"json->sexp": func(args Sexp, ctx map[string]interface{}) interface{} {
+			decoder := json.NewDecoder(strings.NewReader(evalLispArgs(args, ctx)[0].(string)))
+			decoder.UseNumber()
+			value := readJSON(decoder)
+			if _, err := decoder.Token(); err != io.EOF {
+				panic(&LispError{"Invalid JSON: trailing data", nil})
+			}
+			return value
+		},
+		"sexp->json": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return writeJSON(evalLispArgs(args, ctx)[0])
+		},
+		"current-time": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return int(time.Now().Unix())
+		},
+		"time->string": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			layout := time.RFC3339
+			if len(evalledArgs) > 1 {
+				layout = evalledArgs[1].(string)
+			}
+			return time.Unix(int64(evalledArgs[0].(int)), 0).UTC().Format(layout)
+		},
+		"with-timeout": func(args Sexp, ctx map[string]interface{}) interface{} {
+			limit := time.Now().Add(time.Duration(toFloat(evalLisp(args.pair.car, ctx)) * float64(time.Second)))
+			fallback := evalLisp(args.pair.cdr.pair.car, ctx)
+
+			previous, depth, indent := deadline, len(callStack), traceDepth
+			if previous.IsZero() || limit.Before(previous) {
+				deadline = limit
+			}
+
+			result := func() (result interface{}) {
+				defer func() {
+					deadline = previous
+					if r := recover(); r != nil {
+						if timeout, ok := r.(*Timeout); !ok || !timeout.deadline.Equal(limit) {
+							panic(r)
+						}
+						callStack, traceDepth = callStack[:depth], indent
+						result = fallback
+					}
+				}()
+
+				next, result := evalBody(args.pair.cdr.pair.cdr, ctx)
+				if next != nil {
+					result = evalLisp(*next, ctx)
+				}
+				return result
+			}()
+			return result
+		},
+		"sleep": func(args Sexp, ctx map[string]interface{}) interface{} {
+			duration := time.Duration(toFloat(evalLispArgs(args, ctx)[0]) * float64(time.Second))
+			if !deadline.IsZero() && time.Now().Add(duration).After(deadline) {
+				time.Sleep(time.Until(deadline))
+				panic(&Timeout{deadline})
+			}
+			time.Sleep(duration)
+			return void
+		},
+		"getenv": func(args Sexp, ctx map[string]interface{}) interface{} {
+			value, ok := os.LookupEnv(evalLispArgs(args, ctx)[0].(string))
+			if !ok {
+				return nil
+			}
+			return value
+		},
+		"setenv": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			if err := os.Setenv(evalledArgs[0].(string), evalledArgs[1].(string)); err != nil {
+				panic(&LispError{"Cannot set environment variable: " + err.Error(), []interface{}{evalledArgs[0]}})
+			}
+			return evalledArgs[1]
+		},
+		"command-line-args": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var items []interface{}
+			for _, arg := range commandLineArgs {
+				items = append(items, arg)
+			}
+			return sliceToList(items)
+		},
+		"read-file": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return readFile(evalLispArgs(args, ctx)[0].(string))
+		},
+		"read-lines": func(args Sexp, ctx map[string]interface{}) interface{} {
+			contents := readFile(evalLispArgs(args, ctx)[0].(string))
+			if contents == "" {
+				return nil
+			}
+
+			var lines []interface{}
+			for _, line := range strings.Split(strings.TrimSuffix(contents, "\n"), "\n") {
+				lines = append(lines, strings.TrimSuffix(line, "\r"))
+			}
+			return sliceToList(lines)
+		},
+		"append-file": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			path := evalledArgs[0].(string)
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err == nil {
+				_, err = file.WriteString(evalledArgs[1].(string))
+				if closeErr := file.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			if err != nil {
+				panic(&LispError{"Cannot write file: " + err.Error(), []interface{}{path}})
+			}
+			return void
+		},
+		"with-input-from-file": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			path := evalledArgs[0].(string)
+			file, err := os.Open(path)
+			if err != nil {
+				panic(&LispError{"Cannot read file: " + err.Error(), []interface{}{path}})
+			}
+			defer file.Close()
+
+			previous := input
+			input = &InputPort{bufio.NewReader(file), false}
+			defer func() { input = previous }()
+			return funcall(evalledArgs[1], nil, ctx)
+		},
+		"write-file": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			path := evalledArgs[0].(string)
+			if err := os.WriteFile(path, []byte(evalledArgs[1].(string)), 0644); err != nil {
+				panic(&LispError{"Cannot write file: " + err.Error(), []interface{}{path}})
+			}
+			return void
+		},
+		"eval": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			evalCtx := ctx
+			if len(evalledArgs) > 1 {
+				env, ok := evalledArgs[1].(*Environment)
+				if !ok {
+					panic(&LispError{"eval: expected environment, got " + pretty(evalledArgs[1]), nil})
+				}
+				evalCtx = env.ctx
+			}
+			return evalLisp(unquoteSexp(evalledArgs[0]), evalCtx)
+		},
+		"the-environment": func(args Sexp, ctx map[string]interface{}) interface{} {
+			snapshot := map[string]interface{}{}
+			for key, val := range ctx {
+				snapshot[key] = val
+			}
+			return &Environment{snapshot}
+		},
+		"make-base-environment": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return &Environment{map[string]interface{}{}}
+		},
+		"environment?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			_, ok := evalLisp(args.pair.car, ctx).(*Environment)
+			return ok
+		},
+		"quote": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return quoteSexp(args.pair.car)
+		},
+		"begin": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalForm("begin", args, ctx)
+		},
+		"dotimes": func(args Sexp, ctx map[string]interface{}) interface{} {
+			spec := args.pair.car
+			name := spec.pair.car.atom.value
+			count := evalLisp(spec.pair.cdr.pair.car, ctx).(int)
+			loopCtx := copyContext(ctx)
+
+			for i := 0; i < count; i++ {
+				loopCtx[name] = i
+				if args.pair.cdr != nil {
+					evalLispArgs(*args.pair.cdr, loopCtx)
+				}
+			}
+
+			loopCtx[name] = count
+			if result := spec.pair.cdr.pair.cdr; result != nil {
+				return evalLisp(result.pair.car, loopCtx)
+			}
+			return void
+		},
+		"dolist": func(args Sexp, ctx map[string]interface{}) interface{} {
+			spec := args.pair.car
+			name := spec.pair.car.atom.value
+			list := evalLisp(spec.pair.cdr.pair.car, ctx)
+			loopCtx := copyContext(ctx)
+
+			for ; list != nil; list = list.(*Cons).cdr {
+				loopCtx[name] = list.(*Cons).car
+				if args.pair.cdr != nil {
+					evalLispArgs(*args.pair.cdr, loopCtx)
+				}
+			}
+
+			loopCtx[name] = nil
+			if result := spec.pair.cdr.pair.cdr; result != nil {
+				return evalLisp(result.pair.car, loopCtx)
+			}
+			return void
+		},
+		"+": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			checkNumbers("+", evalledArgs, false)
+			if coerceNumbers(evalledArgs) {
+				res := 0.0
+				for _, arg := range evalledArgs {
+					res += arg.(float64)
+				}
+				return res
+			}
+
+			res := 0
+			for _, arg := range evalledArgs {
+				res += arg.(int)
+			}
+
+			return res
+		},
+		"*": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			checkNumbers("*", evalledArgs, false)
+			if coerceNumbers(evalledArgs) {
+				res := 1.0
+				for _, arg := range evalledArgs {
+					res *= arg.(float64)
+				}
+				return res
+			}
+
+			res := 1
+			for _, arg := range evalledArgs {
+				res *= arg.(int)
+			}
+			return res
+		},
+		"-": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var evalledArgs = evalLispArgs(args, ctx)
+			if len(evalledArgs) == 0 {
+				panic("-: expected at least one value")
+			}
+			checkNumbers("-", evalledArgs, false)
+			if coerceNumbers(evalledArgs) {
+				var res = evalledArgs[0].(float64)
+				for _, arg := range evalledArgs[1:] {
+					res -= arg.(float64)
+				}
+				return res
+			}
+
+			var res = evalledArgs[0].(int)
+			var rest = evalledArgs[1:]
+			for _, arg := range rest {
+				res -= arg.(int)
+			}
+			return res
+		},
+		"number?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			switch evalLisp(args.pair.car, ctx).(type) {
+			case int, float64:
+				return true
+			}
+			return false
+		},
+		"exact?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			switch n := evalLisp(args.pair.car, ctx).(type) {
+			case int:
+				return true
+			case float64:
+				return false
+			default:
+				panic("exact?: expected number, got: " + pretty(n))
+			}
+		},
+		"inexact?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			switch n := evalLisp(args.pair.car, ctx).(type) {
+			case int:
+				return false
+			case float64:
+				return true
+			default:
+				panic("inexact?: expected number, got: " + pretty(n))
+			}
+		},
+		"integer?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			switch n := evalLisp(args.pair.car, ctx).(type) {
+			case int:
+				return true
+			case float64:
+				return n == math.Trunc(n) && !math.IsInf(n, 0)
+			}
+			return false
+		},
+		"rational?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			switch n := evalLisp(args.pair.car, ctx).(type) {
+			case int:
+				return true
+			case float64:
+				return !math.IsInf(n, 0) && !math.IsNaN(n)
+			}
+			return false
+		},
+		"real?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			switch evalLisp(args.pair.car, ctx).(type) {
+			case int, float64:
+				return true
+			}
+			return false
+		},
+		"max": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			checkNumbers("max", evalledArgs, false)
+			coerceNumbers(evalledArgs)
+			return extremum("max", evalledArgs, nil, true, ctx)
+		},
+		"min": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			checkNumbers("min", evalledArgs, false)
+			coerceNumbers(evalledArgs)
+			return extremum("min", evalledArgs, nil, false, ctx)
+		},
+		"list-max": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			var key interface{}
+			if len(evalledArgs) > 1 {
+				key = evalledArgs[1]
+			}
+			return extremum("list-max", listToSlice(evalledArgs[0]), key, true, ctx)
+		},
+		"list-min": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			var key interface{}
+			if len(evalledArgs) > 1 {
+				key = evalledArgs[1]
+			}
+			return extremum("list-min", listToSlice(evalledArgs[0]), key, false, ctx)
+		},
+		"abs": func(args Sexp, ctx map[string]interface{}) interface{} {
+			value := evalLisp(args.pair.car, ctx)
+			checkNumbers("abs", []interface{}{value}, false)
+			switch n := value.(type) {
+			case float64:
+				return math.Abs(n)
+			default:
+				if n.(int) < 0 {
+					return -n.(int)
+				}
+				return n
+			}
+		},
+		"sign": func(args Sexp, ctx map[string]interface{}) interface{} {
+			value := evalLisp(args.pair.car, ctx)
+			checkNumbers("sign", []interface{}{value}, false)
+			switch n := value.(type) {
+			case float64:
+				if n < 0 {
+					return -1
+				} else if n > 0 {
+					return 1
+				}
+				return 0
+			default:
+				if n.(int) < 0 {
+					return -1
+				} else if n.(int) > 0 {
+					return 1
+				}
+				return 0
+			}
+		},
+		"negate": func(args Sexp, ctx map[string]interface{}) interface{} {
+			value := evalLisp(args.pair.car, ctx)
+			checkNumbers("negate", []interface{}{value}, false)
+			switch n := value.(type) {
+			case float64:
+				return -n
+			default:
+				return -n.(int)
+			}
+		},
+		"values": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			if len(evalledArgs) == 1 {
+				return evalledArgs[0]
+			}
+			return &Values{evalledArgs}
+		},
+		"call-with-values": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			produced := funcall(evalledArgs[0], nil, ctx)
+			if values, ok := produced.(*Values); ok {
+				return funcall(evalledArgs[1], values.items, ctx)
+			}
+			return funcall(evalledArgs[1], []interface{}{produced}, ctx)
+		},
+		"let-values": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var names []string
+			var values []interface{}
+			for binding := &args.pair.car; binding != nil && binding.pair != nil; binding = binding.pair.cdr {
+				formals := binding.pair.car.pair.car
+				produced := evalLisp(binding.pair.car.pair.cdr.pair.car, ctx)
+				items := []interface{}{produced}
+				if multiple, ok := produced.(*Values); ok {
+					items = multiple.items
+				}
+
+				i := 0
+				for formal := &formals; formal != nil && formal.pair != nil; formal = formal.pair.cdr {
+					if i >= len(items) {
+						panic("Too few values for let-values binding: " + pretty(quoteSexp(formals)))
+					}
+					names = append(names, formal.pair.car.atom.value)
+					values = append(values, items[i])
+					i++
+				}
+				if i != len(items) {
+					panic("Too many values for let-values binding: " + pretty(quoteSexp(formals)))
+				}
+			}
+
+			bodyCtx := copyContext(ctx)
+			for i, name := range names {
+				bodyCtx[name] = values[i]
+			}
+
+			var res interface{}
+			for body := args.pair.cdr; body != nil; body = body.pair.cdr {
+				res = evalLisp(body.pair.car, bodyCtx)
+			}
+			return res
+		},
+		"multiple-value-bind": func(args Sexp, ctx map[string]interface{}) interface{} {
+			produced := evalLisp(args.pair.cdr.pair.car, ctx)
+			items := []interface{}{produced}
+			if multiple, ok := produced.(*Values); ok {
+				items = multiple.items
+			}
+
+			bodyCtx := copyContext(ctx)
+			i := 0
+			for formal := &args.pair.car; formal != nil && formal.pair != nil; formal = formal.pair.cdr {
+				name := formal.pair.car.atom.value
+				bodyCtx[name] = nil
+				if i < len(items) {
+					bodyCtx[name] = items[i]
+				}
+				i++
+			}
+
+			var res interface{}
+			for body := args.pair.cdr.pair.cdr; body != nil; body = body.pair.cdr {
+				res = evalLisp(body.pair.car, bodyCtx)
+			}
+			return res
+		},
+		"floor/": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			checkNumbers("floor/", evalledArgs, true)
+			n, d := evalledArgs[0].(int), evalledArgs[1].(int)
+			if d == 0 {
+				panic("Division by zero")
+			}
+
+			q, r := n/d, n%d
+			if r != 0 && (r < 0) != (d < 0) {
+				q--
+				r += d
+			}
+			return &Values{[]interface{}{q, r}}
+		},
+		"exact-integer-sqrt": func(args Sexp, ctx map[string]interface{}) interface{} {
+			value := evalLisp(args.pair.car, ctx)
+			checkNumbers("exact-integer-sqrt", []interface{}{value}, true)
+			n := value.(int)
+			if n < 0 {
+				panic(fmt.Sprintf("Expected non-negative integer, got: %d", n))
+			}
+
+			root := n
+			if n > 1 {
+				root = n/2 + 1
+				for next := (root + n/root) / 2; next < root; next = (root + n/root) / 2 {
+					root = next
+				}
+			}
+			return &Values{[]interface{}{root, n - root*root}}
+		},
+		"integer-log": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			checkNumbers("integer-log", evalledArgs, true)
+			n, base := evalledArgs[0].(int), evalledArgs[1].(int)
+			if n < 1 || base < 2 {
+				panic(fmt.Sprintf("Expected positive integer and base of at least 2, got: %d %d", n, base))
+			}
+
+			log := 0
+			for n >= base {
+				n /= base
+				log++
+			}
+			return log
+		},
+		"trace": func(args Sexp, ctx map[string]interface{}) interface{} {
+			name := args.pair.car.atom.value
+			original := evalLisp(args.pair.car, ctx)
+			traced[name] = original
+			ctx[name] = func(callArgs Sexp, callCtx map[string]interface{}) interface{} {
+				evalledCallArgs := evalLispArgs(callArgs, callCtx)
+				indent := strings.Repeat("  ", traceDepth)
+				fmt.Fprintf(output, "%s%d: %s\n", indent, traceDepth, pretty(&Cons{Symbol(name), sliceToList(evalledCallArgs)}))
+
+				traceDepth++
+				result := func() interface{} {
+					defer func() { traceDepth-- }()
+					return funcall(original, evalledCallArgs, callCtx)
+				}()
+
+				fmt.Fprintf(output, "%s%d: %s returned %s\n", indent, traceDepth, name, pretty(result))
+				return result
+			}
+			return Symbol(name)
+		},
+		"untrace": func(args Sexp, ctx map[string]interface{}) interface{} {
+			name := args.pair.car.atom.value
+			original, ok := traced[name]
+			if !ok {
+				panic("Function is not traced: " + name)
+			}
+
+			delete(traced, name)
+			ctx[name] = original
+			return Symbol(name)
+		},
+		"char-upcase": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return Char(unicode.ToUpper(rune(evalLisp(args.pair.car, ctx).(Char))))
+		},
+		"char-downcase": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return Char(unicode.ToLower(rune(evalLisp(args.pair.car, ctx).(Char))))
+		},
+		"char-foldcase": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return Char(foldRune(rune(evalLisp(args.pair.car, ctx).(Char))))
+		},
+		"string-foldcase": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var folded strings.Builder
+			for _, r := range evalLisp(args.pair.car, ctx).(string) {
+				if full, ok := fullCaseFolds[r]; ok {
+					folded.WriteString(full)
+				} else {
+					folded.WriteRune(foldRune(r))
+				}
+			}
+			return folded.String()
+		},
+		"char->digit": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			radix := 10
+			if len(evalledArgs) > 1 {
+				radix = evalledArgs[1].(int)
+			}
+			if radix < 2 || radix > 36 {
+				panic(fmt.Sprintf("Expected radix between 2 and 36, got: %d", radix))
+			}
+
+			digit := strings.IndexRune(digits, unicode.ToLower(rune(evalledArgs[0].(Char))))
+			if digit == -1 || digit >= radix {
+				return nil
+			}
+			return digit
+		},
+		"digit->char": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			radix := 10
+			if len(evalledArgs) > 1 {
+				radix = evalledArgs[1].(int)
+			}
+			if radix < 2 || radix > 36 {
+				panic(fmt.Sprintf("Expected radix between 2 and 36, got: %d", radix))
+			}
+
+			digit := evalledArgs[0].(int)
+			if digit < 0 || digit >= radix {
+				return nil
+			}
+			return Char(digits[digit])
+		},
+		"string-map": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			var out strings.Builder
+			for _, c := range evalledArgs[1].(string) {
+				out.WriteRune(rune(funcall(evalledArgs[0], []interface{}{Char(c)}, ctx).(Char)))
+			}
+			return out.String()
+		},
+		"string-for-each": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for _, c := range evalledArgs[1].(string) {
+				funcall(evalledArgs[0], []interface{}{Char(c)}, ctx)
+			}
+			return void
+		},
+		"string-append": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var result strings.Builder
+			for i, arg := range evalLispArgs(args, ctx) {
+				s, ok := arg.(string)
+				if !ok {
+					panic(&LispError{fmt.Sprintf("string-append: expected string in position %d, got %s %s", i+1, typeName(arg), pretty(arg)), nil})
+				}
+				result.WriteString(s)
+			}
+			return result.String()
+		},
+		"string-split": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			limit := -1
+			if len(evalledArgs) > 2 {
+				n, ok := evalledArgs[2].(int)
+				if !ok || n == 0 {
+					panic("Expected non-zero integer limit: " + pretty(evalledArgs[2]))
+				}
+				limit = n
+			}
+
+			var fields []string
+			switch {
+			case len(evalledArgs) > 3 && truthy(evalledArgs[3]):
+				fields = compileRegex(evalledArgs[1].(string)).Split(evalledArgs[0].(string), limit)
+			case len(evalledArgs) > 1:
+				fields = strings.SplitN(evalledArgs[0].(string), evalledArgs[1].(string), limit)
+			default:
+				fields = strings.Fields(evalledArgs[0].(string))
+			}
+
+			var items []interface{}
+			for _, field := range fields {
+				items = append(items, field)
+			}
+			return sliceToList(items)
+		},
+		"string-regex-match": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			re := compileRegex(evalledArgs[0].(string))
+			s := evalledArgs[1].(string)
+			match := re.FindStringSubmatchIndex(s)
+			if match == nil {
+				return nil
+			}
+
+			if re.NumSubexp() == 0 {
+				return s[match[0]:match[1]]
+			}
+
+			var groups []interface{}
+			for i := 0; i < len(match); i += 2 {
+				if match[i] == -1 {
+					groups = append(groups, nil)
+				} else {
+					groups = append(groups, s[match[i]:match[i+1]])
+				}
+			}
+			return sliceToList(groups)
+		},
+		"string-regex-replace": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			re := compileRegex(evalledArgs[0].(string))
+			return re.ReplaceAllString(evalledArgs[1].(string), evalledArgs[2].(string))
+		},
+		"open-input-string": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return &InputPort{bufio.NewReader(strings.NewReader(evalLisp(args.pair.car, ctx).(string))), false}
+		},
+		"read-char": func(args Sexp, ctx map[string]interface{}) interface{} {
+			c, _, err := inputPort(evalLispArgs(args, ctx)).reader.ReadRune()
+			if err != nil {
+				return eof
+			}
+			return Char(c)
+		},
+		"char-ready?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return inputPort(evalLispArgs(args, ctx)).ready()
+		},
+		"read-char-no-hang": func(args Sexp, ctx map[string]interface{}) interface{} {
+			port := inputPort(evalLispArgs(args, ctx))
+			if !port.ready() {
+				return false
+			}
+
+			c, _, err := port.reader.ReadRune()
+			if err != nil {
+				return eof
+			}
+			return Char(c)
+		},
+		"peek-char": func(args Sexp, ctx map[string]interface{}) interface{} {
+			reader := inputPort(evalLispArgs(args, ctx)).reader
+			c, _, err := reader.ReadRune()
+			if err != nil {
+				return eof
+			}
+			reader.UnreadRune()
+			return Char(c)
+		},
+		"read-line": func(args Sexp, ctx map[string]interface{}) interface{} {
+			line, err := inputPort(evalLispArgs(args, ctx)).reader.ReadString('\n')
+			if err != nil && line == "" {
+				return eof
+			}
+			return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		},
+		"eof-object": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return eof
+		},
+		"void": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return void
+		},
+		"void?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalLisp(args.pair.car, ctx) == void
+		},
+		"eof-object?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalLisp(args.pair.car, ctx) == eof
+		},
+		"format": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return format(evalledArgs[0].(string), evalledArgs[1:])
+		},
+		"display": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			fmt.Fprint(outputPort(evalledArgs[1:]), display(evalledArgs[0]))
+			return void
+		},
+		"write": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			fmt.Fprint(outputPort(evalledArgs[1:]), pretty(evalledArgs[0]))
+			return void
+		},
+		"write-shared": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			fmt.Fprint(outputPort(evalledArgs[1:]), prettyLabelled(evalledArgs[0], findDatumLabels(evalledArgs[0], true)))
+			return void
+		},
+		"pp": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			width := 60
+			if len(evalledArgs) > 1 {
+				width = evalledArgs[1].(int)
+			}
+			fmt.Fprintln(output, prettyLayout(evalledArgs[0], 0, 0, width))
+			return void
+		},
+		"write-char": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			fmt.Fprint(outputPort(evalledArgs[1:]), string(evalledArgs[0].(Char)))
+			return void
+		},
+		"newline": func(args Sexp, ctx map[string]interface{}) interface{} {
+			fmt.Fprintln(outputPort(evalLispArgs(args, ctx)))
+			return void
+		},
+		"open-output-string": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return &OutputPort{&strings.Builder{}}
+		},
+		"get-output-string": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalLisp(args.pair.car, ctx).(*OutputPort).writer.(*strings.Builder).String()
+		},
+		"println": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var items []string
+			for _, arg := range evalLispArgs(args, ctx) {
+				items = append(items, display(arg))
+			}
+			fmt.Fprintln(output, strings.Join(items, " "))
+			return void
+		},
+		"error": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			panic(&LispError{evalledArgs[0].(string), evalledArgs[1:]})
+		},
+		"try": func(args Sexp, ctx map[string]interface{}) interface{} {
+			result, caught, failed := catchError(func() interface{} {
+				return evalLisp(args.pair.car, ctx)
+			})
+			if !failed {
+				return result
+			}
+
+			handler := args.pair.cdr.pair.car
+			if handler.kind != Pair || handler.pair.car.atom.value != "catch" {
+				panic("Expected (catch name body...) clause in try, got: " + pretty(quoteSexp(handler)))
+			}
+
+			name := handler.pair.cdr.pair.car.atom.value
+			handlerCtx := copyContext(ctx)
+			handlerCtx[name] = caught
+
+			var res interface{}
+			for body := handler.pair.cdr.pair.cdr; body != nil; body = body.pair.cdr {
+				res = evalLisp(body.pair.car, handlerCtx)
+			}
+			return res
+		},
+		"guard": func(args Sexp, ctx map[string]interface{}) interface{} {
+			result, caught, failed := catchError(func() interface{} {
+				var res interface{}
+				for body := args.pair.cdr; body != nil; body = body.pair.cdr {
+					res = evalLisp(body.pair.car, ctx)
+				}
+				return res
+			})
+			if !failed {
+				return result
+			}
+
+			spec := args.pair.car
+			name := spec.pair.car.atom.value
+			handlerCtx := copyContext(ctx)
+			handlerCtx[name] = caught
+
+			if result, ok := evalCond(spec.pair.cdr, handlerCtx); ok {
+				return result
+			}
+			raise(caught)
+			return nil
+		},
+		"dynamic-wind": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			funcall(evalledArgs[0], nil, ctx)
+			defer func() {
+				if r := recover(); r != nil {
+					if _, ok := r.(*Timeout); ok {
+						saved := deadline
+						deadline = time.Time{}
+						defer func() { deadline = saved }()
+					}
+					funcall(evalledArgs[2], nil, ctx)
+					panic(r)
+				}
+			}()
+
+			result := funcall(evalledArgs[1], nil, ctx)
+			funcall(evalledArgs[2], nil, ctx)
+			return result
+		},
+		"raise": func(args Sexp, ctx map[string]interface{}) interface{} {
+			raise(evalLisp(args.pair.car, ctx))
+			return nil
+		},
+		"with-exception-handler": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			result, caught, failed := catchError(func() interface{} {
+				return funcall(evalledArgs[1], nil, ctx)
+			})
+			if !failed {
+				return result
+			}
+			return funcall(evalledArgs[0], []interface{}{caught}, ctx)
+		},
+		"error-object?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			_, ok := evalLisp(args.pair.car, ctx).(*LispError)
+			return ok
+		},
+		"error-object-message": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return errorObject("error-object-message", evalLisp(args.pair.car, ctx)).message
+		},
+		"error-object-irritants": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return sliceToList(errorObject("error-object-irritants", evalLisp(args.pair.car, ctx)).irritants)
+		},
+		"when-debug": func(args Sexp, ctx map[string]interface{}) interface{} {
+			if !debug {
+				return nil
+			}
+
+			var res interface{}
+			for body := &args; body != nil && body.pair != nil; body = body.pair.cdr {
+				res = evalLisp(body.pair.car, ctx)
+			}
+			return res
+		},
+		"assert": func(args Sexp, ctx map[string]interface{}) interface{} {
+			if !assertionsEnabled {
+				return nil
+			}
+
+			evalledArgs := evalLispArgs(args, ctx)
+			if !truthy(evalledArgs[0]) {
+				if len(evalledArgs) > 1 {
+					panic(&LispError{"Assertion failed: " + display(evalledArgs[1]), nil})
+				}
+				panic(&LispError{"Assertion failed: " + pretty(quoteSexp(args.pair.car)), nil})
+			}
+			return true
+		},
+		"assert-equal": func(args Sexp, ctx map[string]interface{}) interface{} {
+			if !assertionsEnabled {
+				return nil
+			}
+
+			evalledArgs := evalLispArgs(args, ctx)
+			if !equalValues(evalledArgs[0], evalledArgs[1]) {
+				panic(&LispError{fmt.Sprintf("assert-equal: expected %s, got %s", pretty(evalledArgs[0]), pretty(evalledArgs[1])), nil})
+			}
+			return true
+		},
+		"assert-approx": func(args Sexp, ctx map[string]interface{}) interface{} {
+			if !assertionsEnabled {
+				return nil
+			}
+
+			evalledArgs := evalLispArgs(args, ctx)
+			if !approxEqual(evalledArgs) {
+				panic(&LispError{fmt.Sprintf("assert-approx: expected %s, got %s", pretty(evalledArgs[0]), pretty(evalledArgs[1])), nil})
+			}
+			return true
+		},
+		"approx=?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return approxEqual(evalLispArgs(args, ctx))
+		},
+		"test": func(args Sexp, ctx map[string]interface{}) interface{} {
+			description := display(evalLisp(args.pair.car, ctx))
+			expected := evalLisp(args.pair.cdr.pair.car, ctx)
+			actual, caught, failed := catchError(func() interface{} {
+				return evalLisp(args.pair.cdr.pair.cdr.pair.car, ctx)
+			})
+			switch {
+			case failed:
+				testFailures++
+				fmt.Fprintf(output, "FAIL: %s: raised %s\n", description, display(caught))
+			case equalValues(expected, actual):
+				testPasses++
+				fmt.Fprintf(output, "PASS: %s\n", description)
+			default:
+				testFailures++
+				fmt.Fprintf(output, "FAIL: %s: expected %s, got %s\n", description, pretty(expected), pretty(actual))
+			}
+			return void
+		},
+		"test-summary": func(args Sexp, ctx map[string]interface{}) interface{} {
+			fmt.Fprintf(output, "%d passed, %d failed\n", testPasses, testFailures)
+			return testFailures == 0
+		},
+		"assert-type": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			name := string(evalledArgs[1].(Symbol))
+			predicate, ok := typePredicates[name]
+			if !ok {
+				panic("Unknown type: " + name)
+			}
+			if assertionsEnabled && !predicate(evalledArgs[0]) {
+				panic(&LispError{"assert-type: expected " + name + ", got", []interface{}{evalledArgs[0]}})
+			}
+			return evalledArgs[0]
+		},
+		"check-property": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			trials := 100
+			if len(evalledArgs) > 2 {
+				trials = evalledArgs[2].(int)
+			}
+
+			fails := func(value interface{}) bool {
+				result, _, failed := catchError(func() interface{} {
+					return funcall(evalledArgs[1], []interface{}{value}, ctx)
+				})
+				return failed || !truthy(result)
+			}
+
+			for i := 1; i <= trials; i++ {
+				value := funcall(evalledArgs[0], nil, ctx)
+				if !fails(value) {
+					continue
+				}
+
+			shrink:
+				for steps := 0; steps < 1000; steps++ {
+					for _, candidate := range shrinkCandidates(value) {
+						if fails(candidate) {
+							value = candidate
+							continue shrink
+						}
+					}
+					break
+				}
+				fmt.Fprintf(output, "Property failed on trial %d: %s\n", i, pretty(value))
+				return false
+			}
+			return true
+		},
+		"assert-error": func(args Sexp, ctx map[string]interface{}) interface{} {
+			if !assertionsEnabled {
+				return nil
+			}
+
+			evalledArgs := evalLispArgs(args, ctx)
+			result, caught, failed := catchError(func() interface{} {
+				return funcall(evalledArgs[0], nil, ctx)
+			})
+
+			if !failed {
+				panic(&LispError{"assert-error: expected an error, but thunk returned: " + pretty(result), nil})
+			}
+
+			if message := display(caught); len(evalledArgs) > 1 && !strings.Contains(message, evalledArgs[1].(string)) {
+				panic(&LispError{fmt.Sprintf("assert-error: expected error containing %q, got: %q", evalledArgs[1], message), nil})
+			}
+
+			return true
+		},
+		"list": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return sliceToList(evalLispArgs(args, ctx))
+		},
+		"cons": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return &Cons{evalledArgs[0], evalledArgs[1]}
+		},
+		"car": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalLisp(args.pair.car, ctx).(*Cons).car
+		},
+		"cdr": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalLisp(args.pair.car, ctx).(*Cons).cdr
+		},
+		"set-car!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			evalledArgs[0].(*Cons).car = evalledArgs[1]
+			return evalledArgs[1]
+		},
+		"set-cdr!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			evalledArgs[0].(*Cons).cdr = evalledArgs[1]
+			return evalledArgs[1]
+		},
+		"list-set!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			list, index := evalledArgs[0], evalledArgs[1].(int)
+			for i := 0; index >= 0 && list != nil; i++ {
+				cell := list.(*Cons)
+				if i == index {
+					cell.car = evalledArgs[2]
+					return evalledArgs[2]
+				}
+				list = cell.cdr
+			}
+			panic(fmt.Sprintf("List index out of range: %d", index))
+		},
+		"null?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalLisp(args.pair.car, ctx) == nil
+		},
+		"eq?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return eqValues(evalledArgs[0], evalledArgs[1])
+		},
+		"boolean=?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for _, arg := range evalledArgs {
+				if _, ok := arg.(bool); !ok {
+					panic("boolean=?: expected boolean, got: " + pretty(arg))
+				}
+			}
+			for _, arg := range evalledArgs[1:] {
+				if arg != evalledArgs[0] {
+					return false
+				}
+			}
+			return true
+		},
+		"symbol=?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for _, arg := range evalledArgs {
+				if _, ok := arg.(Symbol); !ok {
+					panic("symbol=?: expected symbol, got: " + pretty(arg))
+				}
+			}
+			for _, arg := range evalledArgs[1:] {
+				if arg != evalledArgs[0] {
+					return false
+				}
+			}
+			return true
+		},
+		"eqv?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return eqvValues(evalledArgs[0], evalledArgs[1])
+		},
+		"equal?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return equalValues(evalledArgs[0], evalledArgs[1])
+		},
+		"assoc": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for _, item := range listToSlice(evalledArgs[1]) {
+				if equalValues(item.(*Cons).car, evalledArgs[0]) {
+					return item
+				}
+			}
+			return nil
+		},
+		"alist-ref": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for _, item := range listToSlice(evalledArgs[0]) {
+				if equalValues(item.(*Cons).car, evalledArgs[1]) {
+					return item.(*Cons).cdr
+				}
+			}
+			if len(evalledArgs) > 2 {
+				return evalledArgs[2]
+			}
+			return nil
+		},
+		"assv": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for _, item := range listToSlice(evalledArgs[1]) {
+				if eqvValues(item.(*Cons).car, evalledArgs[0]) {
+					return item
+				}
+			}
+			return nil
+		},
+		"memv": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for list := evalledArgs[1]; list != nil; list = list.(*Cons).cdr {
+				if eqvValues(list.(*Cons).car, evalledArgs[0]) {
+					return list
+				}
+			}
+			return nil
+		},
+		"assoc-set": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			key := evalledArgs[1]
+			var items []interface{}
+			found := false
+			for _, item := range listToSlice(evalledArgs[0]) {
+				if !found && equalValues(item.(*Cons).car, key) {
+					item = &Cons{key, evalledArgs[2]}
+					found = true
+				}
+				items = append(items, item)
+			}
+
+			if !found {
+				items = append(items, &Cons{key, evalledArgs[2]})
+			}
+			return sliceToList(items)
+		},
+		"assoc-remove": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			var items []interface{}
+			for _, item := range listToSlice(evalledArgs[0]) {
+				if !equalValues(item.(*Cons).car, evalledArgs[1]) {
+					items = append(items, item)
+				}
+			}
+			return sliceToList(items)
+		},
+		"copy": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return copyValue(evalLisp(args.pair.car, ctx))
+		},
+		"last-pair": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return lastPair(evalLisp(args.pair.car, ctx))
+		},
+		"last": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return lastPair(evalLisp(args.pair.car, ctx)).car
+		},
+		"butlast": func(args Sexp, ctx map[string]interface{}) interface{} {
+			items := listToSlice(evalLisp(args.pair.car, ctx))
+			if len(items) == 0 {
+				return nil
+			}
+			return sliceToList(items[:len(items)-1])
+		},
+		"flatten": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var items []interface{}
+			stack := []interface{}{evalLisp(args.pair.car, ctx)}
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				cell, ok := top.(*Cons)
+				if !ok {
+					if top != nil {
+						items = append(items, top)
+					}
+					continue
+				}
+
+				stack = append(stack, cell.cdr, cell.car)
+			}
+			return sliceToList(items)
+		},
+		"apply": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			last := len(evalledArgs) - 1
+			callArgs := append(append([]interface{}{}, evalledArgs[1:last]...), listToSlice(evalledArgs[last])...)
+			return funcall(evalledArgs[0], callArgs, ctx)
+		},
+		"identity": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return evalLisp(args.pair.car, ctx)
+		},
+		"compose": func(args Sexp, ctx map[string]interface{}) interface{} {
+			fns := evalLispArgs(args, ctx)
+			return func(callArgs Sexp, callCtx map[string]interface{}) interface{} {
+				evalledCallArgs := evalLispArgs(callArgs, callCtx)
+				if len(fns) == 0 {
+					return evalledCallArgs[0]
+				}
+
+				result := funcall(fns[len(fns)-1], evalledCallArgs, callCtx)
+				for i := len(fns) - 2; i >= 0; i-- {
+					result = funcall(fns[i], []interface{}{result}, callCtx)
+				}
+				return result
+			}
+		},
+		"partial": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			fn, preset := evalledArgs[0], evalledArgs[1:]
+			return func(callArgs Sexp, callCtx map[string]interface{}) interface{} {
+				evalledCallArgs := evalLispArgs(callArgs, callCtx)
+				return funcall(fn, append(append([]interface{}{}, preset...), evalledCallArgs...), callCtx)
+			}
+		},
+		"map": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			fn, lists := evalledArgs[0], evalledArgs[1:]
+			head := &Cons{}
+			tail := head
+			for len(lists) > 0 {
+				var callArgs []interface{}
+				for i, list := range lists {
+					if list == nil {
+						return head.cdr
+					}
+					callArgs = append(callArgs, list.(*Cons).car)
+					lists[i] = list.(*Cons).cdr
+				}
+				cell := &Cons{funcall(fn, callArgs, ctx), nil}
+				tail.cdr = cell
+				tail = cell
+			}
+			return nil
+		},
+		"append": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			if len(evalledArgs) == 0 {
+				return nil
+			}
+
+			var items []interface{}
+			for _, list := range evalledArgs[:len(evalledArgs)-1] {
+				items = append(items, listToSlice(list)...)
+			}
+
+			result := evalledArgs[len(evalledArgs)-1]
+			for i := len(items) - 1; i >= 0; i-- {
+				result = &Cons{items[i], result}
+			}
+			return result
+		},
+		// append! splices its arguments together by pointing the last cell
+		// of each list at the next one, so every argument but the last is
+		// modified in place.
+		"append!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var result interface{}
+			var last *Cons
+			for _, list := range evalLispArgs(args, ctx) {
+				if list == nil {
+					continue
+				}
+
+				if last == nil {
+					result = list
+				} else {
+					last.cdr = list
+				}
+
+				cell, ok := list.(*Cons)
+				for ok {
+					last = cell
+					cell, ok = cell.cdr.(*Cons)
+				}
+			}
+			return result
+		},
+		"reverse": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var result interface{}
+			for list := evalLisp(args.pair.car, ctx); list != nil; list = list.(*Cons).cdr {
+				result = &Cons{list.(*Cons).car, result}
+			}
+			return result
+		},
+		// reverse! turns the list around by rewiring each cell's cdr, so the
+		// argument is destroyed and only the returned list should be used.
+		"reverse!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var result interface{}
+			list := evalLisp(args.pair.car, ctx)
+			for list != nil {
+				cell := list.(*Cons)
+				list = cell.cdr
+				cell.cdr = result
+				result = cell
+			}
+			return result
+		},
+		"for-each": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for list := evalledArgs[1]; list != nil; list = list.(*Cons).cdr {
+				funcall(evalledArgs[0], []interface{}{list.(*Cons).car}, ctx)
+			}
+			return void
+		},
+		"filter": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			head := &Cons{}
+			tail := head
+			for list := evalledArgs[1]; list != nil; list = list.(*Cons).cdr {
+				item := list.(*Cons).car
+				if truthy(funcall(evalledArgs[0], []interface{}{item}, ctx)) {
+					cell := &Cons{item, nil}
+					tail.cdr = cell
+					tail = cell
+				}
+			}
+			return head.cdr
+		},
+		"find": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			for list := evalledArgs[1]; list != nil; list = list.(*Cons).cdr {
+				item := list.(*Cons).car
+				if truthy(funcall(evalledArgs[0], []interface{}{item}, ctx)) {
+					return item
+				}
+			}
+			return nil
+		},
+		"list-index": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			i := 0
+			for list := evalledArgs[1]; list != nil; list = list.(*Cons).cdr {
+				if truthy(funcall(evalledArgs[0], []interface{}{list.(*Cons).car}, ctx)) {
+					return i
+				}
+				i++
+			}
+			return nil
+		},
+		"span": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return span(evalledArgs[0], evalledArgs[1], true, ctx)
+		},
+		"break": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			return span(evalledArgs[0], evalledArgs[1], false, ctx)
+		},
+		"group-by": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			var keys []interface{}
+			var groups [][]interface{}
+		outer:
+			for list := evalledArgs[1]; list != nil; list = list.(*Cons).cdr {
+				item := list.(*Cons).car
+				key := funcall(evalledArgs[0], []interface{}{item}, ctx)
+				for i := range keys {
+					if equalValues(keys[i], key) {
+						groups[i] = append(groups[i], item)
+						continue outer
+					}
+				}
+				keys = append(keys, key)
+				groups = append(groups, []interface{}{item})
+			}
+
+			var result []interface{}
+			for i := range keys {
+				result = append(result, &Cons{keys[i], sliceToList(groups[i])})
+			}
+			return sliceToList(result)
+		},
+		"merge": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			left, right := evalledArgs[0], evalledArgs[1]
+			var items []interface{}
+			for left != nil && right != nil {
+				a, b := left.(*Cons), right.(*Cons)
+				if truthy(funcall(evalledArgs[2], []interface{}{b.car, a.car}, ctx)) {
+					items = append(items, b.car)
+					right = b.cdr
+				} else {
+					items = append(items, a.car)
+					left = a.cdr
+				}
+			}
+			items = append(items, listToSlice(left)...)
+			items = append(items, listToSlice(right)...)
+			return sliceToList(items)
+		},
+		"fold": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			acc := evalledArgs[1]
+			for list := evalledArgs[2]; list != nil; list = list.(*Cons).cdr {
+				acc = funcall(evalledArgs[0], []interface{}{list.(*Cons).car, acc}, ctx)
+			}
+			return acc
+		},
+		"unfold": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			stop, mapper, successor, seed := evalledArgs[0], evalledArgs[1], evalledArgs[2], evalledArgs[3]
+			var items []interface{}
+			for !truthy(funcall(stop, []interface{}{seed}, ctx)) {
+				items = append(items, funcall(mapper, []interface{}{seed}, ctx))
+				seed = funcall(successor, []interface{}{seed}, ctx)
+			}
+			return sliceToList(items)
+		},
+		"iota": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			start, step := 0, 1
+			if len(evalledArgs) > 1 {
+				start = evalledArgs[1].(int)
+			}
+			if len(evalledArgs) > 2 {
+				step = evalledArgs[2].(int)
+			}
+
+			items := make([]interface{}, evalledArgs[0].(int))
+			for i := range items {
+				items[i] = start + i*step
+			}
+			return sliceToList(items)
+		},
+		"zip": func(args Sexp, ctx map[string]interface{}) interface{} {
+			lists := evalLispArgs(args, ctx)
+			var tuples []interface{}
+			for len(lists) > 0 {
+				var tuple []interface{}
+				for i, list := range lists {
+					if list == nil {
+						return sliceToList(tuples)
+					}
+					tuple = append(tuple, list.(*Cons).car)
+					lists[i] = list.(*Cons).cdr
+				}
+				tuples = append(tuples, sliceToList(tuple))
+			}
+			return nil
+		},
+		"unzip": func(args Sexp, ctx map[string]interface{}) interface{} {
+			tuples := listToSlice(evalLisp(args.pair.car, ctx))
+			if len(tuples) == 0 {
+				return nil
+			}
+
+			columns := make([][]interface{}, len(listToSlice(tuples[0])))
+			for _, tuple := range tuples {
+				for i, item := range listToSlice(tuple) {
+					columns[i] = append(columns[i], item)
+				}
+			}
+
+			var lists []interface{}
+			for _, column := range columns {
+				lists = append(lists, sliceToList(column))
+			}
+			return sliceToList(lists)
+		},
+		"plist-get": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			items := listToSlice(evalledArgs[0])
+			for i := 0; i+1 < len(items); i += 2 {
+				if eqValues(items[i], evalledArgs[1]) {
+					return items[i+1]
+				}
+			}
+			return nil
+		},
+		"plist-put": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			items := listToSlice(evalledArgs[0])
+			for i := 0; i+1 < len(items); i += 2 {
+				if eqValues(items[i], evalledArgs[1]) {
+					items[i+1] = evalledArgs[2]
+					return sliceToList(items)
+				}
+			}
+			return sliceToList(append(items, evalledArgs[1], evalledArgs[2]))
+		},
+		"vector-sort!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			items := evalledArgs[0].(*Vector).items
+			sort.Slice(items, func(i, j int) bool {
+				return truthy(funcall(evalledArgs[1], []interface{}{items[i], items[j]}, ctx))
+			})
+			return evalledArgs[0]
+		},
+		"sort-by": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			items := listToSlice(evalledArgs[1])
+			keys := make([]interface{}, len(items))
+			for i, item := range items {
+				keys[i] = funcall(evalledArgs[0], []interface{}{item}, ctx)
+			}
+
+			order := make([]int, len(items))
+			for i := range order {
+				order[i] = i
+			}
+			sort.SliceStable(order, func(i, j int) bool {
+				if len(evalledArgs) > 2 {
+					return truthy(funcall(evalledArgs[2], []interface{}{keys[order[i]], keys[order[j]]}, ctx))
+				}
+				return lessThan(keys[order[i]], keys[order[j]])
+			})
+
+			sorted := make([]interface{}, len(items))
+			for i, index := range order {
+				sorted[i] = items[index]
+			}
+			return sliceToList(sorted)
+		},
+		"make-hash": func(args Sexp, ctx map[string]interface{}) interface{} {
+			ordered := false
+			if args.pair != nil {
+				ordered = evalLisp(args.pair.car, ctx) == Symbol("ordered")
+			}
+			return &HashTable{map[interface{}]interface{}{}, ordered, nil}
+		},
+		"hash-ref": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			value, ok := evalledArgs[0].(*HashTable).items[evalledArgs[1]]
+			if !ok && len(evalledArgs) > 2 {
+				return evalledArgs[2]
+			}
+			return value
+		},
+		"hash-table-ref!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			table := evalledArgs[0].(*HashTable)
+			if value, ok := table.items[evalledArgs[1]]; ok {
+				return value
+			}
+
+			value := funcall(evalledArgs[2], nil, ctx)
+			table.set(evalledArgs[1], value)
+			return value
+		},
+		"hash-set!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			evalledArgs[0].(*HashTable).set(evalledArgs[1], evalledArgs[2])
+			return evalledArgs[2]
+		},
+		"hash-remove!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			evalledArgs[0].(*HashTable).remove(evalledArgs[1])
+			return void
+		},
+		"hash-table-count": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return len(evalLisp(args.pair.car, ctx).(*HashTable).items)
+		},
+		"hash-table-empty?": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return len(evalLisp(args.pair.car, ctx).(*HashTable).items) == 0
+		},
+		"hash-table-clear!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalLisp(args.pair.car, ctx).(*HashTable).clear()
+			return void
+		},
+		"hash-keys": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return sliceToList(evalLisp(args.pair.car, ctx).(*HashTable).keys())
+		},
+		"hash-table->list": func(args Sexp, ctx map[string]interface{}) interface{} {
+			table := evalLisp(args.pair.car, ctx).(*HashTable)
+			var items []interface{}
+			for _, key := range table.keys() {
+				items = append(items, &Cons{key, table.items[key]})
+			}
+			return sliceToList(items)
+		},
+		"hash-for-each": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			table := evalledArgs[0].(*HashTable)
+			for _, key := range table.keys() {
+				funcall(evalledArgs[1], []interface{}{key, table.items[key]}, ctx)
+			}
+			return void
+		},
+		"hash-update!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			table := evalledArgs[0].(*HashTable)
+			key := evalledArgs[1]
+			current, ok := table.items[key]
+			if !ok {
+				if len(evalledArgs) < 4 {
+					panic("Key not found in hash table: " + pretty(key))
+				}
+				current = evalledArgs[3]
+			}
+
+			updated := funcall(evalledArgs[2], []interface{}{current}, ctx)
+			table.set(key, updated)
+			return updated
+		},
+		"bytevector": func(args Sexp, ctx map[string]interface{}) interface{} {
+			var items []byte
+			for _, arg := range evalLispArgs(args, ctx) {
+				items = append(items, toByte(arg))
+			}
+			return &Bytevector{items}
+		},
+		"make-bytevector": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			length := evalledArgs[0].(int)
+			if length < 0 {
+				panic(fmt.Sprintf("Expected non-negative bytevector length, got: %d", length))
+			}
+
+			var fill byte
+			if len(evalledArgs) > 1 {
+				fill = toByte(evalledArgs[1])
+			}
+
+			items := make([]byte, length)
+			for i := range items {
+				items[i] = fill
+			}
+			return &Bytevector{items}
+		},
+		"bytevector-length": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return len(evalLisp(args.pair.car, ctx).(*Bytevector).bytes)
+		},
+		"bytevector-u8-ref": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			bytevector := evalledArgs[0].(*Bytevector)
+			return int(bytevector.bytes[bytevectorIndex(bytevector, evalledArgs[1])])
+		},
+		"bytevector-u8-set!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			bytevector := evalledArgs[0].(*Bytevector)
+			bytevector.bytes[bytevectorIndex(bytevector, evalledArgs[1])] = toByte(evalledArgs[2])
+			return evalledArgs[2]
+		},
+		"string->utf8": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return &Bytevector{[]byte(evalLisp(args.pair.car, ctx).(string))}
+		},
+		"utf8->string": func(args Sexp, ctx map[string]interface{}) interface{} {
+			bytevector := evalLisp(args.pair.car, ctx).(*Bytevector)
+			if !utf8.Valid(bytevector.bytes) {
+				panic(&LispError{"utf8->string: invalid UTF-8 in", []interface{}{bytevector}})
+			}
+			return string(bytevector.bytes)
+		},
+		"hash": func(args Sexp, ctx map[string]interface{}) interface{} {
+			h := fnv.New64a()
+			hashValue(h, evalLisp(args.pair.car, ctx))
+			return int(h.Sum64() >> 1)
+		},
+		"vector": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return &Vector{evalLispArgs(args, ctx)}
+		},
+		"make-vector": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			length := evalledArgs[0].(int)
+			if length < 0 {
+				panic(fmt.Sprintf("Expected non-negative vector length, got: %d", length))
+			}
+
+			var fill interface{} = 0
+			if len(evalledArgs) > 1 {
+				fill = evalledArgs[1]
+			}
+
+			items := make([]interface{}, length)
+			for i := range items {
+				items[i] = fill
+			}
+			return &Vector{items}
+		},
+		"vector-length": func(args Sexp, ctx map[string]interface{}) interface{} {
+			return len(evalLisp(args.pair.car, ctx).(*Vector).items)
+		},
+		"vector-ref": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			items := evalledArgs[0].(*Vector).items
+			i := evalledArgs[1].(int)
+			if i < 0 || i >= len(items) {
+				panic(fmt.Sprintf("Vector index out of range: %d", i))
+			}
+			return items[i]
+		},
+		"vector-set!": func(args Sexp, ctx map[string]interface{}) interface{} {
+			evalledArgs := evalLispArgs(args, ctx)
+			items := evalledArgs[0].(*Vector).items
+			i := evalledArgs[1].(int)
+			if i < 0 || i >= len(items) {
+				panic(fmt.Sprintf("Vector index out of range: %d", i))
+			}
+			items[i] = evalledArgs[2]
+			return evalledArgs[2]
+		},
+	}
+	builtins["apropos"] = func(args Sexp, ctx map[string]interface{}) interface{} {
+		substring := evalLisp(args.pair.car, ctx).(string)
+		names := map[string]bool{}
+		for name := range builtins {
+			names[name] = true
+		}
+		for name := range ctx {
+			names[name] = true
+		}
+
+		var matches []string
+		for name := range names {
+			if strings.Contains(name, substring) {
+				matches = append(matches, name)
+			}
+		}
+		sort.Strings(matches)
+
+		var items []interface{}
+		for _, name := range matches {
+			items = append(items, Symbol(name))
+		}
+		return sliceToList(items)
+	}
+
+	builtins["describe"] = func(args Sexp, ctx map[string]interface{}) interface{} {
+		name := string(evalLisp(args.pair.car, ctx).(Symbol))
+		value, ok := ctx[name]
+		if constant, isConstant := value.(*Constant); isConstant {
+			value = constant.value
+		}
+		if !ok {
+			if _, ok := builtins[name]; ok {
+				fmt.Fprintf(output, "%s is a builtin\n", name)
+			} else {
+				fmt.Fprintf(output, "%s is unbound\n", name)
+			}
+			return void
+		}
+
+		lambda, ok := value.(*Lambda)
+		if !ok {
+			fmt.Fprintf(output, "%s is bound to %s\n", name, pretty(value))
+			return void
+		}
+
+		arity := 0
+		for iter := &lambda.params; iter != nil && iter.pair != nil; iter = iter.pair.cdr {
+			arity++
+		}
+		fmt.Fprintf(output, "%s is a user-defined procedure with arity %d\n", name, arity)
+		if lambda.body == nil || lambda.body.pair == nil || lambda.body.pair.cdr == nil {
+			return void
+		}
+		if doc := lambda.body.pair.car; doc.kind == Atom && doc.atom.kind == String {
+			fmt.Fprintf(output, "  %s\n", doc.atom.value)
+		}
+		return void
+	}
+}
+
+type Value struct {
+	value interface{}
+}
+
+func (v Value) Int() (int, bool) {
+	i, ok := v.value.(int)
+	return i, ok
+}
+
+func (v Value) Bool() (bool, bool) {
+	b, ok := v.value.(bool)
+	return b, ok
+}
+
+func (v Value) String() (string, bool) {
+	s, ok := v.value.(string)
+	return s, ok
+}
+
+func (v Value) List() ([]Value, bool) {
+	var items []Value
+	for list := v.value; list != nil; list = list.(*Cons).cdr {
+		if _, ok := list.(*Cons); !ok {
+			return nil, false
+		}
+		items = append(items, Value{list.(*Cons).car})
+	}
+	return items, true
+}
+
+func (v Value) IsNil() bool {
+	return v.value == nil
+}
+
+func (v Value) IsVoid() bool {
+	return v.value == void
+}
+
+var evalMutex sync.Mutex
+
+// Eval runs program and returns the value of its last form. The
+// interpreter keeps its state in package variables, so calls are
+// serialized: concurrent callers wait for each other rather than share it.
+func Eval(program string) (result Value, err error) {
+	evalMutex.Lock()
+	defer evalMutex.Unlock()
+	return evalProgram(program)
+}
+
+// evalProgram is Eval for callers that already hold evalMutex.
+func evalProgram(program string) (result Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if trace := backtrace(program, callStack); trace != "" {
+				err = fmt.Errorf("%v\n%s", r, trace)
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	callStack = nil
+	testPasses, testFailures = 0, 0
+	source, warnedDefinitions = program, map[int]bool{}
+	tokens := lex(program)
+	begin := Sexp{Atom, &Token{"begin", Identifier, -1}, nil, nil}
+	begin = sexpAppend(&begin, nil)
+	cursor, child := parse(tokens, 0, map[string]interface{}{})
+	begin = sexpAppend(&begin, &child)
 	for cursor != len(tokens)-1 {
-		cursor, child = parse(tokens, cursor+1)
+		cursor, child = parse(tokens, cursor+1, map[string]interface{}{})
 		begin = sexpAppend(&begin, &child)
 	}
-	result := evalLisp(begin, map[string]interface{}{})
-	fmt.Println(result)
+	return Value{evalLisp(begin, map[string]interface{}{})}, nil
+}
+
+func lineDiff(expected string, actual string) string {
+	expectedLines := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	actualLines := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+	line := func(lines []string, i int) string {
+		if i < len(lines) {
+			return strconv.Quote(lines[i])
+		}
+		return "(missing)"
+	}
+
+	var diff strings.Builder
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		if want, got := line(expectedLines, i), line(actualLines, i); want != got {
+			fmt.Fprintf(&diff, "  line %d:\n    expected: %s\n    actual:   %s\n", i+1, want, got)
+		}
+	}
+	return diff.String()
+}
+
+// runCorpus evaluates every .lisp file under dir and compares what it prints,
+// including the final result as the command line would show it, with the
+// .out file beside it.
+func runCorpus(dir string) (passed int, failed int, err error) {
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".lisp" {
+			return err
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		expected, err := os.ReadFile(strings.TrimSuffix(path, ".lisp") + ".out")
+		if err != nil {
+			failed++
+			fmt.Fprintf(output, "FAIL: %s: %v\n", path, err)
+			return nil
+		}
+
+		var actual strings.Builder
+		evalMutex.Lock()
+		previous := output
+		output = &actual
+		result, evalErr := evalProgram(string(contents))
+		output = previous
+		evalMutex.Unlock()
+		if evalErr == nil && result.value != void {
+			actual.WriteString(pretty(result.value) + "\n")
+		}
+
+		switch {
+		case evalErr != nil:
+			failed++
+			fmt.Fprintf(output, "FAIL: %s: raised %v\n", path, evalErr)
+		case actual.String() != string(expected):
+			failed++
+			fmt.Fprintf(output, "FAIL: %s\n%s", path, lineDiff(string(expected), actual.String()))
+		default:
+			passed++
+			fmt.Fprintf(output, "PASS: %s\n", path)
+		}
+		return nil
+	})
+	return passed, failed, err
+}
+
+func main() {
+	flag.BoolVar(&foldCase, "fold-case", false, "Fold identifiers to lowercase")
+	flag.BoolVar(&debug, "debug", false, "Evaluate when-debug forms")
+	flag.IntVar(&floatPrecision, "float-precision", -1, "Significant digits to print floats with, or -1 for the shortest exact form")
+	flag.BoolVar(&strict, "strict", false, "Treat misplaced internal definitions as errors")
+	disableAsserts := flag.Bool("disable-asserts", false, "Skip assertions without evaluating their arguments")
+	testFile := flag.String("test", "", "Run the test forms in a file and report a summary")
+	corpusDir := flag.String("corpus", "", "Run each .lisp file in a directory and compare its output with the matching .out file")
+	flag.Parse()
+	assertionsEnabled = !*disableAsserts
+
+	if *corpusDir != "" {
+		passed, failed, err := runCorpus(*corpusDir)
+		fmt.Fprintf(output, "%d passed, %d failed\n", passed, failed)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *testFile != "" {
+		contents, err := os.ReadFile(*testFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		commandLineArgs = flag.Args()
+		_, err = Eval(string(contents))
+		fmt.Printf("%d passed, %d failed\n", testPasses, testFailures)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if testFailures > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	program := flag.Arg(0)
+	if flag.NArg() > 1 {
+		commandLineArgs = flag.Args()[1:]
+	}
+	if contents, err := os.ReadFile(program); err == nil {
+		program = string(contents)
+	}
+
+	result, err := Eval(program)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if result.value != void {
+		fmt.Println(pretty(result.value))
+	}
 }
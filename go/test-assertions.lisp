@@ -0,0 +1,12 @@
+(test "assert-type returns a matching value" 5 (assert-type 5 'integer))
+(test "assert-type accepts a float as a number" 1.5 (assert-type 1.5 'number))
+(test "assert-type accepts a proper list" '(1 2) (assert-type (list 1 2) 'list))
+(test "assert-type accepts the empty list as a list" '() (assert-type (list) 'list))
+(def thunk (lambda () 1))
+(test "assert-type accepts a lambda as a procedure" thunk (assert-type thunk 'procedure))
+(test "assert-type rejects a mismatch" #t
+  (assert-error (lambda () (assert-type "x" 'integer)) "assert-type: expected integer, got"))
+(test "assert-type rejects an improper list as a list" #t
+  (assert-error (lambda () (assert-type (cons 1 2) 'list)) "expected list"))
+(test "assert-type rejects an unknown type" #t
+  (assert-error (lambda () (assert-type 5 'widget)) "Unknown type: widget"))
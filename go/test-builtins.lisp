@@ -0,0 +1,8 @@
+(test "list builds a list" '(1 2 3) (list 1 2 3))
+(test "append joins lists" '(1 2 3 4) (append (list 1 2) (list 3 4)))
+(test "hash-ref falls back to the default" 0 (hash-ref (make-hash) 'missing 0))
+(test "string-split splits on whitespace" '("a" "b") (string-split " a b "))
+
+(def my-even? (lambda (n) (if (eqv? n 0) #t (my-odd? (- n 1)))))
+(def my-odd? (lambda (n) (if (eqv? n 0) #f (my-even? (- n 1)))))
+(test "mutually recursive definitions" (list #t #t #f) (list (my-even? 10) (my-odd? 7) (my-even? 3)))
@@ -0,0 +1,31 @@
+(test "literal" (bytevector 1 2 255) #u8(1 2 255))
+(test "empty literal" 0 (bytevector-length #u8()))
+(test "quoted literal" #u8(7) '#u8(7))
+(test "make-bytevector fills" #u8(9 9 9) (make-bytevector 3 9))
+(test "make-bytevector defaults to zero" #u8(0 0) (make-bytevector 2))
+(test "printed form" "#u8(1 2 3)" (format "~a" #u8(1 2 3)))
+(test "length" 3 (bytevector-length #u8(4 5 6)))
+(test "ref" 5 (bytevector-u8-ref #u8(4 5 6) 1))
+
+(def bv (make-bytevector 3 0))
+(bytevector-u8-set! bv 0 10)
+(bytevector-u8-set! bv 2 255)
+(test "set!" #u8(10 0 255) bv)
+(test "copy is independent" #u8(10 0 255)
+  (let ((c (copy bv)))
+    (begin (bytevector-u8-set! c 0 1) bv)))
+(assert-type bv 'bytevector)
+
+(assert-error (lambda () (bytevector-u8-ref bv 3)) "out of range")
+(assert-error (lambda () (bytevector-u8-set! bv 3 0)) "out of range")
+(assert-error (lambda () (bytevector-u8-set! bv 0 256)) "Expected byte")
+(assert-error (lambda () (make-bytevector 2 300)) "Expected byte")
+(assert-error (lambda () (bytevector 1 "a")) "Expected byte")
+
+(test "string->utf8 ascii" #u8(104 105) (string->utf8 "hi"))
+(test "string->utf8 multi-byte" #u8(206 187 226 130 172) (string->utf8 "λ€"))
+(test "utf8->string round trip ascii" "hello" (utf8->string (string->utf8 "hello")))
+(test "utf8->string round trip multi-byte" "naïve λ €" (utf8->string (string->utf8 "naïve λ €")))
+(test "utf8->string empty" "" (utf8->string #u8()))
+(assert-error (lambda () (utf8->string #u8(104 255 105))) "invalid UTF-8")
+(assert-error (lambda () (utf8->string #u8(206))) "invalid UTF-8")
@@ -0,0 +1,18 @@
+(define-constant limit 3)
+(test "a constant evaluates to its value" 3 limit)
+(test "set! on a constant raises" "Cannot set constant: limit"
+  (try (set! limit 4) (catch e (error-object-message e))))
+(test "def can't redefine a constant in its own scope" "Cannot redefine constant: limit"
+  (try (def limit 4) (catch e (error-object-message e))))
+(test "define-constant can't redefine a constant" "Cannot redefine constant: limit"
+  (try (define-constant limit 4) (catch e (error-object-message e))))
+(test "set! on an inherited constant raises" #t
+  (assert-error (lambda () (set! limit 4)) "Cannot set constant"))
+(test "a let binding shadowing a constant is mutable" 6
+  (let ((limit 5)) (set! limit 6) limit))
+(test "def inside a lambda shadows a constant" 10
+  ((lambda () (def limit 9) (set! limit 10) limit)))
+(test "the constant is unchanged after shadowing" 3 limit)
+(test "an ordinary variable can be set!" 2 (let ((count 1)) (set! count 2) count))
+(test "set! on an unbound variable raises" #t
+  (assert-error (lambda () (set! never-defined 1)) "Unbound variable"))
@@ -0,0 +1,49 @@
+(def counter (make-hash))
+(def bump! (lambda () (hash-update! counter 'n (lambda (n) (+ n 1)) 0)))
+
+(dotimes (i 5) (bump!))
+(test "dotimes runs the body n times" 5 (hash-ref counter 'n))
+(def indices (open-output-string))
+(dotimes (i 4) (display i indices))
+(test "dotimes binds i from 0 to n-1" "0123" (get-output-string indices))
+(test "dotimes with zero iterations skips the body" #t (void? (dotimes (i 0) (error "ran"))))
+(test "dotimes returns its result form" 'done (dotimes (i 3 'done) i))
+(def i 'outer)
+(dotimes (i 2) i)
+(test "dotimes doesn't leak its variable" 'outer i)
+
+(def total (make-hash))
+(dolist (x (list 1 2 3 4)) (hash-update! total 'sum (lambda (s) (+ s x)) 0))
+(test "dolist accumulates over the list" 10 (hash-ref total 'sum))
+(test "dolist over an empty list skips the body" #t (void? (dolist (x (list)) (error "ran"))))
+(test "dolist returns its result form" 'res (dolist (x (list 1 2) 'res) x))
+(def x 'outer)
+(dolist (x (list 1 2)) x)
+(test "dolist doesn't leak its variable" 'outer x)
+(dotimes (i 2) (def dotimes-leaked i))
+(test "a def in a dotimes body stays in the loop" 'unbound (try dotimes-leaked (catch e 'unbound)))
+(dolist (x (list 1 2)) (def dolist-leaked x))
+(test "a def in a dolist body stays in the loop" 'unbound (try dolist-leaked (catch e 'unbound)))
+
+(test "let-values binds both results of floor/" '(3 2)
+  (let-values (((q r) (floor/ 17 5))) (list q r)))
+(test "let-values binds several clauses" 17
+  (let-values (((q r) (floor/ 17 5)) ((d) (values 5))) (+ (* q d) r)))
+(let-values (((q r) (floor/ 17 5))) (def let-values-leaked q))
+(test "a def in a let-values body stays in the body" 'unbound (try let-values-leaked (catch e 'unbound)))
+(test "def returns void" #t (void? (def defined-for-void 1)))
+(test "set! returns void" #t (void? (set! defined-for-void 2)))
+(test "for-each returns void" #t (void? (for-each car (list (list 1)))))
+(test "display returns void" #t (void? (display "" (open-output-string))))
+(test "void returns void" #t (void? (void)))
+(test "the empty list is not void" #f (void? (list)))
+(test "#f is not void" #f (void? #f))
+(test "multiple-value-bind binds each value" '(3 1) (multiple-value-bind (q r) (floor/ 7 2) (list q r)))
+(test "multiple-value-bind fills missing values with nil" '(1 ()) (multiple-value-bind (a b) (values 1) (list a b)))
+(test "multiple-value-bind drops extra values" 1 (multiple-value-bind (a) (values 1 2) a))
+(test "multiple-value-bind treats a single value as one result" '(5 ()) (multiple-value-bind (a b) 5 (list a b)))
+(test "multiple-value-bind runs a multi-form body" 2
+  (multiple-value-bind (a) (values 1) (+ a 0) (+ a 1)))
+(multiple-value-bind (a b) (values 1 2) (def multiple-value-bind-leaked b))
+(test "a def in a multiple-value-bind body stays in the body" 'unbound
+  (try multiple-value-bind-leaked (catch e 'unbound)))
@@ -0,0 +1,18 @@
+(def original (list 1 (list 2 3) (vector 4 (list 5))))
+(def copied (copy original))
+(test "copy of a list is equal" #t (equal? original copied))
+(test "copy of a list is a new list" #f (eq? original copied))
+(test "copy reaches nested lists" #f (eq? (car (cdr original)) (car (cdr copied))))
+(set-car! (car (cdr copied)) 'changed)
+(vector-set! (car (cdr (cdr copied))) 0 'changed)
+(test "mutating a copied nested list leaves the original alone" '(2 3) (car (cdr original)))
+(test "mutating a copied vector leaves the original alone" 4 (vector-ref (car (cdr (cdr original))) 0))
+
+(def table (make-hash))
+(hash-set! table 'items (list 1 2))
+(def table-copy (copy table))
+(hash-set! table-copy 'extra #t)
+(set-car! (hash-ref table-copy 'items) 9)
+(test "a copied hash table has its own keys" 1 (hash-table-count table))
+(test "a copied hash table copies its values" '(1 2) (hash-ref table 'items))
+(test "copy leaves atoms as they are" "text" (copy "text"))
@@ -0,0 +1,79 @@
+(def circular (lambda (items)
+  (let ((head (copy items)))
+    (begin
+      (set-cdr! (last-pair head) head)
+      head))))
+
+(def c (circular (list 1 2 3)))
+(test "circular list is equal? to itself" #t (equal? c c))
+(test "two circular lists with the same cycle" #t (equal? c (circular (list 1 2 3))))
+(test "circular lists unrolled differently" #t (equal? c (circular (list 1 2 3 1 2 3))))
+(test "circular lists with different elements" #f (equal? c (circular (list 1 2 4))))
+(test "circular and proper list" #f (equal? c (list 1 2 3)))
+
+(def cc (copy c))
+(test "copy of a circular list is fresh" #f (eq? cc c))
+(test "copy of a circular list is circular" #t (eq? (cdr (cdr (cdr cc))) cc))
+(test "copy of a circular list is equal?" #t (equal? cc c))
+
+(def shared (list 1 2))
+(def sc (copy (list shared shared)))
+(test "copy preserves sharing" #t (eq? (car sc) (car (cdr sc))))
+(test "copy of shared structure is fresh" #f (eq? (car sc) shared))
+
+(def v (vector 1 2))
+(vector-set! v 1 v)
+(def vc (copy v))
+(test "circular vector is equal? to itself" #t (equal? v v))
+(test "copy of a circular vector is circular" #t (eq? (vector-ref vc 1) vc))
+(test "copy of a circular vector is equal?" #t (equal? vc v))
+
+(def written (lambda (write-procedure value)
+  (let ((port (open-output-string)))
+    (begin
+      (write-procedure value port)
+      (get-output-string port)))))
+
+(test "write labels a circular list" "#0=(1 2 3 . #0#)" (written write c))
+(test "write labels a cycle through a car" "#0=(1 #0# 3)"
+  (let ((l (list 1 2 3)))
+    (begin (set-car! (cdr l) l) (written write l))))
+(test "write labels a cycle in the tail" "(1 . #0=(2 3 . #0#))"
+  (let ((l (list 1 2 3)))
+    (begin (set-cdr! (cdr (cdr l)) (cdr l)) (written write l))))
+(test "write labels a circular vector" "#0=#(1 #0#)" (written write v))
+(test "write leaves acyclic sharing alone" "((1 2) (1 2))" (written write (list shared shared)))
+(test "write-shared labels acyclic sharing" "(#0=(1 2) #0# #(#0#))" (written write-shared (list shared shared (vector shared))))
+(test "write-shared labels a circular list" "#0=(1 2 3 . #0#)" (written write-shared c))
+(test "format ~s labels a circular list" "#0=(1 2 3 . #0#)" (format "~s" c))
+
+(def read-shared '(#0=(1 2) #0# #1=(3) #1#))
+(test "reader shares a labelled datum" #t (eq? (car read-shared) (car (cdr read-shared))))
+(test "reader keeps labels apart" #f (eq? (car read-shared) (car (cdr (cdr read-shared)))))
+(test "reader shares a second label" #t (eq? (car (cdr (cdr read-shared))) (car (cdr (cdr (cdr read-shared))))))
+
+(def read-circular '#0=(1 2 3 . #0#))
+(test "reader builds a circular list" #t (eq? read-circular (cdr (cdr (cdr read-circular)))))
+(test "circular literal is equal? to a built circular list" #t (equal? read-circular c))
+(test "circular literal writes back the same way" "#0=(1 2 3 . #0#)" (written write read-circular))
+
+(def read-car-cycle '#0=(a #0# c))
+(test "reader builds a cycle through a car" #t (eq? read-car-cycle (car (cdr read-car-cycle))))
+
+(def read-circular-vector '#0=#(1 #0#))
+(test "reader builds a circular vector" #t (eq? (vector-ref read-circular-vector 1) read-circular-vector))
+(test "circular vector literal writes back the same way" "#0=#(1 #0#)" (written write read-circular-vector))
+(def read-shared-vector '(#0=(1 2) #0# #(#0#)))
+(test "reader shares a label inside a vector" #t (eq? (car read-shared-vector) (vector-ref (car (cdr (cdr read-shared-vector))) 0)))
+(test "reader reads dotted pairs" 2 (cdr '(1 . 2)))
+
+(test "a circular list is not a proper list" "assert-type: expected list, got"
+  (try (assert-type (circular (list 1 2 3)) 'list) (catch e (error-object-message e))))
+(test "a one-element circular list is not a proper list" "assert-type: expected list, got"
+  (try (assert-type (circular (list 1)) 'list) (catch e (error-object-message e))))
+(test "a circular list with a proper prefix is not a proper list" "assert-type: expected list, got"
+  (try (assert-type (cons 0 (circular (list 1 2))) 'list) (catch e (error-object-message e))))
+(test "an even-length proper list is a list" '(1 2 3 4) (assert-type (list 1 2 3 4) 'list))
+(test "an odd-length proper list is a list" '(1 2 3) (assert-type (list 1 2 3) 'list))
+(test "a circular list can't serialize to JSON" "Cannot serialize an improper list to JSON"
+  (try (sexp->json (circular (list 1 2))) (catch e (error-object-message e))))
@@ -0,0 +1,37 @@
+(def traced-wind (lambda (thunk)
+  (let ((port (open-output-string)))
+    (begin
+      (try
+        (dynamic-wind
+          (lambda () (display "before " port))
+          (lambda () (thunk port))
+          (lambda () (display "after" port)))
+        (catch e (display " caught" port)))
+      (get-output-string port)))))
+
+(test "after runs on normal return" "before during after"
+  (traced-wind (lambda (port) (display "during " port))))
+(test "dynamic-wind returns the thunk's value" 42
+  (dynamic-wind (lambda () 1) (lambda () 42) (lambda () 2)))
+(test "after runs when the thunk errors" "before after caught"
+  (traced-wind (lambda (port) (error "boom"))))
+(test "after runs when a raised value escapes" "before after caught"
+  (traced-wind (lambda (port) (raise 'escape))))
+(test "the escaping value is preserved" 'escape
+  (guard (e (#t e))
+    (dynamic-wind (lambda () 1) (lambda () (raise 'escape)) (lambda () 2))))
+(test "nested afters run innermost first" "before before after after caught"
+  (traced-wind (lambda (port)
+    (dynamic-wind
+      (lambda () (display "before " port))
+      (lambda () (error "boom"))
+      (lambda () (display "after " port))))))
+(test "after runs when a timeout escapes" "before after"
+  (let ((port (open-output-string)))
+    (begin
+      (with-timeout 0.05 'timed-out
+        (dynamic-wind
+          (lambda () (display "before " port))
+          (lambda () (sleep 10))
+          (lambda () (display "after" port))))
+      (get-output-string port))))
@@ -0,0 +1,6 @@
+(test "getenv of an unset variable" '() (getenv "LISP_TEST_SURELY_UNSET"))
+(test "setenv returns the value" "on" (setenv "LISP_TEST_FLAG" "on"))
+(test "getenv sees a variable set with setenv" "on" (getenv "LISP_TEST_FLAG"))
+(setenv "LISP_TEST_FLAG" "")
+(test "getenv distinguishes empty from unset" "" (getenv "LISP_TEST_FLAG"))
+(test "setenv rejects an invalid name" #t (assert-error (lambda () (setenv "" "x")) "Cannot set environment variable"))
@@ -0,0 +1,25 @@
+(test "boolean=? with matching booleans" #t (boolean=? #t #t #t))
+(test "boolean=? with matching #f" #t (boolean=? #f #f))
+(test "boolean=? with different booleans" #f (boolean=? #t #t #f))
+(test "boolean=? rejects non-booleans" #t (assert-error (lambda () (boolean=? 1 1)) "expected boolean"))
+(test "symbol=? with matching symbols" #t (symbol=? 'a 'a 'a))
+(test "symbol=? with different symbols" #f (symbol=? 'a 'a 'b))
+(test "symbol=? rejects non-symbols" #t (assert-error (lambda () (symbol=? "a" "a")) "expected symbol"))
+(test "eqv? compares integers by value" #t (eqv? 100000 (+ 99999 1)))
+(test "eqv? compares floats by value" #t (eqv? 2.5 (+ 2.0 0.5)))
+(test "eqv? compares chars by value" #t (eqv? #\A (char-upcase #\a)))
+(test "eqv? does not mix exact and inexact numbers" #f (eqv? 2 2.0))
+(test "eq? treats 0.0 and -0.0 as the same" #t (eq? 0.0 (negate 0.0)))
+(test "eqv? keeps 0.0 and -0.0 apart" #f (eqv? 0.0 (negate 0.0)))
+(test "eqv? on separately built lists" #f (eqv? (list 1 (list 2)) (list 1 (list 2))))
+(test "equal? on separately built lists" #t (equal? (list 1 (list 2)) (list 1 (list 2))))
+(test "eqv? on separately built vectors" #f (eqv? (vector 1 2) (vector 1 2)))
+(test "equal? on separately built vectors" #t (equal? (vector 1 2) (vector 1 2)))
+(test "eqv? on the same list" #t (let ((l (list 1 2))) (eqv? l l)))
+(test "memv finds a float" (list 2.5 3) (memv 2.5 (list 1 2.5 3)))
+(test "memv skips -0.0 when looking for 0.0" (list 0.0) (memv 0.0 (list (negate 0.0) 0.0)))
+(test "assv on char keys" (cons #\b 2) (assv #\b (list (cons #\a 1) (cons #\b 2))))
+(test "case matches with eqv?" "half" (case 0.5 ((0.5) "half") (else "other")))
+(def same-procedure (lambda () 1))
+(test "equal? on the same procedure" #t (equal? same-procedure same-procedure))
+(test "equal? on separately built procedures" #f (equal? (lambda () 1) (lambda () 1)))
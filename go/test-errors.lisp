@@ -0,0 +1,52 @@
+(test "assert-error passes when a builtin raises" #t
+  (assert-error (lambda () (car 1))))
+(test "assert-error matches a message substring" #t
+  (assert-error (lambda () (error "disk full")) "full"))
+(test "assert-error fails when the thunk returns normally" "returned"
+  (try (assert-error (lambda () 1))
+    (catch e (if (string-regex-match "thunk returned" (error-object-message e)) "returned" "other"))))
+(test "assert-error fails when the message doesn't match" 'mismatch
+  (try (assert-error (lambda () (error "boom")) "bang")
+    (catch e 'mismatch)))
+
+(def caught (try (error "bad thing" 1 'two) (catch e e)))
+(test "a caught error is an error object" #t (error-object? caught))
+(test "error-object-message" "bad thing" (error-object-message caught))
+(test "error-object-irritants" '(1 two) (error-object-irritants caught))
+(test "an error with no irritants" '() (error-object-irritants (try (error "plain") (catch e e))))
+(test "builtin failures are error objects" #t (try (car 1) (catch e (error-object? e))))
+(test "a raised non-error value is not an error object" #f (try (raise 'x) (catch e (error-object? e))))
+
+(test "guard handles a matching raise" '(handled 42)
+  (guard (e ((number? e) (list 'handled e))) (raise 42)))
+(test "guard returns the body's value when nothing is raised" 3
+  (guard (e (#t 'unused)) (+ 1 2)))
+(test "guard falls through to else" 'other
+  (guard (e ((number? e) 'number) (else 'other)) (raise 'x)))
+(test "guard re-raises when no clause matches" '(outer x)
+  (try (guard (e ((number? e) 'number)) (raise 'x))
+    (catch e (list 'outer e))))
+(test "guard catches error objects" "bad"
+  (guard (e ((error-object? e) (error-object-message e))) (error "bad")))
+
+(test "with-exception-handler returns the thunk's value" 7
+  (with-exception-handler (lambda (e) 'unused) (lambda () 7)))
+(test "with-exception-handler escapes with the handler's value" '(handled oops)
+  (with-exception-handler (lambda (e) (list 'handled e)) (lambda () (raise 'oops) 'unreached)))
+(test "a handler can re-raise to an outer handler" '(outer (inner oops))
+  (with-exception-handler
+    (lambda (e) (list 'outer e))
+    (lambda ()
+      (with-exception-handler
+        (lambda (e) (raise (list 'inner e)))
+        (lambda () (raise 'oops))))))
+
+(try (raise 1) (catch e (def try-leaked e)))
+(guard (e (#t (def guard-leaked e))) (raise 2))
+(test "a def in a try handler stays in the handler" 'unbound (try try-leaked (catch e 'unbound)))
+(test "a def in a guard clause stays in the clause" 'unbound (try guard-leaked (catch e 'unbound)))
+
+(test "error-object-message rejects a non-error" "error-object-message: expected error object, got symbol x"
+  (try (error-object-message 'x) (catch e (error-object-message e))))
+(test "error-object-irritants rejects a non-error" "error-object-irritants: expected error object, got integer 42"
+  (guard (e (#t (error-object-message e))) (error-object-irritants 42)))
@@ -0,0 +1,16 @@
+(def x 1)
+(def captured (the-environment))
+(test "eval without an environment uses the caller's scope" 2 (let ((x 2)) (eval 'x)))
+(test "a captured environment sees its own binding" '(2 1) (let ((x 2)) (list x (eval 'x captured))))
+(def x 3)
+(test "a captured environment is a snapshot" 1 (eval 'x captured))
+(test "environment? on a captured environment" #t (environment? captured))
+(test "environment? on a non-environment" #f (environment? 5))
+(test "eval rejects a non-environment" #t
+  (assert-error (lambda () (eval '(+ 1 2) 5)) "eval: expected environment, got 5"))
+
+(def base (make-base-environment))
+(test "a base environment has the builtins" 3 (eval '(+ 1 2) base))
+(test "a base environment lacks user definitions" #t
+  (assert-error (lambda () (eval 'x base)) "Undefined value :x"))
+(test "a base environment is an environment" #t (environment? base))
@@ -0,0 +1,53 @@
+(def count-to-three
+  (make-generator
+    (lambda (yield)
+      (yield 1)
+      (yield 2)
+      (yield 3))))
+
+(test "first yield" 1 (count-to-three))
+(test "second yield" 2 (count-to-three))
+(test "third yield" 3 (count-to-three))
+(test "exhausted" #t (eof-object? (count-to-three)))
+(test "stays exhausted" #t (eof-object? (count-to-three)))
+
+(def naturals
+  (make-generator
+    (lambda (yield)
+      (def loop (lambda (n) (begin (yield n) (loop (+ n 1)))))
+      (loop 0))))
+
+(test "infinite generator step 1" 0 (naturals))
+(test "infinite generator step 2" 1 (naturals))
+(test "infinite generator step 3" 2 (naturals))
+(generator-close naturals)
+(test "closed generator is exhausted" #t (eof-object? (naturals)))
+
+(def failing
+  (make-generator
+    (lambda (yield)
+      (yield 1)
+      (error "generator failed"))))
+
+(test "yield before an error" 1 (failing))
+(assert-error (lambda () (failing)) "generator failed")
+(assert-type failing (quote procedure))
+
+(def unwound (open-output-string))
+(def wound
+  (make-generator
+    (lambda (yield)
+      (dynamic-wind
+        (lambda () (display "in " unwound))
+        (lambda () (yield 1) (yield 2))
+        (lambda () (display "out" unwound))))))
+
+(test "yield inside dynamic-wind" 1 (wound))
+(generator-close wound)
+(test "closing runs the after thunk before returning" "in out" (get-output-string unwound))
+(def timed (make-generator (lambda (yield) (with-timeout 10 'late (yield 1) (yield 2)))))
+(test "yield inside with-timeout" 1 (timed))
+(test "closing a generator inside with-timeout" #t (void? (generator-close timed)))
+(def brief (make-generator (lambda (yield) (with-timeout 0.01 'late (yield 1) (yield 2)))))
+(test "a generator's timeout doesn't follow its yield back to the caller" 'slept
+  (begin (brief) (sleep 0.02) 'slept))
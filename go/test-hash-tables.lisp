@@ -0,0 +1,59 @@
+(def count-words (lambda (words)
+  (let ((counts (make-hash)))
+    (begin
+      (for-each (lambda (word) (hash-update! counts word (lambda (n) (+ n 1)) 0)) words)
+      counts))))
+
+(def counts (count-words (string-split "the cat saw the other cat and the dog")))
+(test "hash-update! counts repeated words" 3 (hash-ref counts "the"))
+(test "hash-update! counts a word seen twice" 2 (hash-ref counts "cat"))
+(test "hash-update! starts from the default" 1 (hash-ref counts "dog"))
+(test "hash-update! returns the stored value" 5
+  (let ((h (make-hash)))
+    (hash-update! h 'k (lambda (n) (+ n 5)) 0)))
+
+(def ordered (make-hash 'ordered))
+(hash-set! ordered 'c 1)
+(hash-set! ordered 'a 2)
+(hash-set! ordered 'b 3)
+(hash-set! ordered 'a 4)
+(test "ordered hash-keys follow insertion order" '(c a b) (hash-keys ordered))
+(test "ordered hash-table->list follows insertion order" '((c . 1) (a . 4) (b . 3)) (hash-table->list ordered))
+(def visited (open-output-string))
+(hash-for-each ordered (lambda (k v) (display k visited)))
+(test "ordered hash-for-each follows insertion order" "cab" (get-output-string visited))
+(hash-remove! ordered 'c)
+(hash-set! ordered 'c 5)
+(test "a removed and re-added key moves to the end" '(a b c) (hash-keys ordered))
+
+(def counted (make-hash))
+(test "a new table is empty" #t (hash-table-empty? counted))
+(test "a new table has no entries" 0 (hash-table-count counted))
+(hash-set! counted 'a 1)
+(hash-set! counted 'b 2)
+(hash-set! counted 'a 3)
+(test "hash-table-count counts distinct keys" 2 (hash-table-count counted))
+(test "a filled table is not empty" #f (hash-table-empty? counted))
+(hash-table-clear! counted)
+(test "hash-table-clear! removes every entry" 0 (hash-table-count counted))
+(test "a cleared table is empty" #t (hash-table-empty? counted))
+(test "a cleared table forgets its keys" 'missing (hash-ref counted 'a 'missing))
+
+(def defaults (make-hash))
+(hash-set! defaults 'present 1)
+(hash-set! defaults 'falsy #f)
+(test "hash-ref ignores the default for a present key" 1 (hash-ref defaults 'present 0))
+(test "hash-ref returns the default for a missing key" 0 (hash-ref defaults 'missing 0))
+(test "hash-ref keeps a stored #f over the default" #f (hash-ref defaults 'falsy 0))
+(test "hash-ref without a default returns nil" '() (hash-ref defaults 'missing))
+(test "alist-ref finds a key" 1 (alist-ref (list (cons 'a 1)) 'a 9))
+(test "alist-ref returns the default for a missing key" 9 (alist-ref (list (cons 'a 1)) 'b 9))
+(test "alist-ref without a default returns nil" '() (alist-ref (list (cons 'a 1)) 'b))
+
+(def calls (make-hash))
+(def memo (make-hash))
+(def compute (lambda () (hash-update! calls 'count (lambda (n) (+ n 1)) 0) 42))
+(test "hash-table-ref! computes a missing value" 42 (hash-table-ref! memo 'answer compute))
+(test "hash-table-ref! stores the computed value" 42 (hash-ref memo 'answer))
+(test "hash-table-ref! reuses the stored value" 42 (hash-table-ref! memo 'answer compute))
+(test "the thunk runs only once" 1 (hash-ref calls 'count))
@@ -0,0 +1,35 @@
+(test "equal integers" (hash 42) (hash 42))
+(test "equal strings" (hash "hello") (hash (string-append "hel" "lo")))
+(test "equal symbols" (hash 'abc) (hash (quote abc)))
+(test "equal chars" (hash #\a) (hash #\a))
+(test "equal booleans" (hash #t) (hash #t))
+(test "equal lists" (hash (list 1 "two" 'three)) (hash (list 1 "two" 'three)))
+(test "equal nested structures" (hash (list (vector 1 2) (list #\x (list)))) (hash (list (vector 1 2) (list #\x (list)))))
+(test "copies hash the same" (hash (list 1 (vector 2 3))) (hash (copy (list 1 (vector 2 3)))))
+
+(test "distinct integers" #f (eq? (hash 1) (hash 2)))
+(test "integer and string" #f (eq? (hash 1) (hash "1")))
+(test "string and symbol" #f (eq? (hash "a") (hash 'a)))
+(test "list order matters" #f (eq? (hash (list 1 2)) (hash (list 2 1))))
+(test "nesting matters" #f (eq? (hash (list (list 1) 2)) (hash (list 1 (list 2)))))
+(test "list and vector" #f (eq? (hash (list 1 2)) (hash (vector 1 2))))
+(test "#t and #f" #f (eq? (hash #t) (hash #f)))
+(assert-type (hash (list 1 2 3)) 'integer)
+
+(def circular (lambda (items)
+  (let ((head (copy items)))
+    (begin
+      (set-cdr! (last-pair head) head)
+      head))))
+(test "-0.0 and 0.0 are equal?" #t (equal? (negate 0.0) 0.0))
+(test "-0.0 hashes like 0.0" (hash 0.0) (hash (negate 0.0)))
+(test "a circular list hashes" #t (integer? (hash (circular (list 1 2 3)))))
+(test "copies of a circular list hash the same" (hash (circular (list 1 2 3))) (hash (circular (list 1 2 3))))
+(test "circular lists with different elements" #f (eq? (hash (circular (list 1 2 3))) (hash (circular (list 1 2 4)))))
+(def sv (vector 1 2))
+(vector-set! sv 0 sv)
+(test "a self-referential vector hashes" #t (integer? (hash sv)))
+(def sv2 (vector 1 2))
+(vector-set! sv2 0 sv2)
+(test "copies of a self-referential vector hash the same" (hash sv) (hash sv2))
+(test "shared but acyclic structure hashes like an unshared copy" (hash (let ((x (list 1))) (list x x))) (hash (list (list 1) (list 1))))
@@ -0,0 +1,24 @@
+(def parsed (json->sexp "{\"a\": [1, 2.5, \"x\", true, null], \"b\": {}}"))
+(test "json->sexp reads an object as a hash table" '("a" "b") (hash-keys parsed))
+(test "json->sexp reads arrays, numbers, strings, booleans and null" (list 1 2.5 "x" #t (list)) (hash-ref parsed "a"))
+(test "json->sexp reads an empty object" 0 (hash-table-count (hash-ref parsed "b")))
+(test "json->sexp rejects truncated input" #t (assert-error (lambda () (json->sexp "[1,")) "Invalid JSON"))
+(test "json->sexp rejects trailing data" #t (assert-error (lambda () (json->sexp "1 2")) "trailing data"))
+
+(def document "{\"a\":[1,2.5,\"x\",true,false],\"b\":{\"c\":1}}")
+(test "JSON round-trips through json->sexp and sexp->json" document (sexp->json (json->sexp document)))
+
+(def object (make-hash 'ordered))
+(hash-set! object 'name "lisp")
+(hash-set! object "tags" (vector 'a #\b))
+(test "sexp->json writes an ordered hash table" "{\"name\":\"lisp\",\"tags\":[\"a\",\"b\"]}" (sexp->json object))
+(test "sexp->json writes an integer" "42" (sexp->json 42))
+(test "sexp->json keeps a whole float inexact" "2.0" (sexp->json 2.0))
+(test "sexp->json escapes strings" "\"a\\\"b\"" (sexp->json "a\"b"))
+(test "sexp->json writes booleans" "[true,false]" (sexp->json (list #t #f)))
+(test "sexp->json writes the empty list as null" "null" (sexp->json (list)))
+(test "sexp->json writes a nested list" "[1,[2]]" (sexp->json (list 1 (list 2))))
+(test "sexp->json rejects an improper list" #t (assert-error (lambda () (sexp->json (cons 1 2))) "improper list"))
+(test "sexp->json rejects a non-string key" #t
+  (assert-error (lambda () (let ((h (make-hash))) (hash-set! h 1 2) (sexp->json h))) "must be a string or symbol"))
+(test "sexp->json rejects a procedure" #t (assert-error (lambda () (sexp->json car)) "Cannot serialize"))
@@ -0,0 +1,111 @@
+(def alist (list (cons 'x 1) (cons 'y 2)))
+(test "assoc-set updates an existing key" (list (cons 'x 9) (cons 'y 2)) (assoc-set alist 'x 9))
+(test "assoc-set adds a missing key" (list (cons 'x 1) (cons 'y 2) (cons 'z 3)) (assoc-set alist 'z 3))
+(test "assoc-remove drops a key" (list (cons 'y 2)) (assoc-remove alist 'x))
+(test "assoc-remove of a missing key is a no-op" alist (assoc-remove alist 'z))
+(test "assoc-set and assoc-remove leave the original unchanged" (list (cons 'x 1) (cons 'y 2))
+  (begin (assoc-set alist 'x 9) (assoc-remove alist 'y) alist))
+
+(test "flatten removes nesting" (list 1 2 3 4) (flatten '(1 (2 (3)) 4)))
+(test "flatten drops empty sublists" (list 1 2) (flatten '(() (()) 1 (() 2))))
+(test "flatten of an empty list" '() (flatten '()))
+(def nest (lambda (n acc) (if (eqv? n 0) acc (nest (- n 1) (list acc)))))
+(test "flatten handles deep nesting" (list 1) (flatten (nest 100000 (list 1))))
+
+(test "zip equal-length lists" '((1 a) (2 b) (3 c)) (zip (list 1 2 3) (list 'a 'b 'c)))
+(test "zip stops at the shortest list" '((1 a)) (zip (list 1 2 3) (list 'a)))
+(test "zip three lists" '((1 a x) (2 b y)) (zip (list 1 2) (list 'a 'b 'c) (list 'x 'y)))
+(test "unzip inverts zip" '((1 2) (a b)) (unzip (zip (list 1 2) (list 'a 'b))))
+
+(test "map over two lists" '(11 22 33) (map + (list 1 2 3) (list 10 20 30)))
+(test "map over two lists of differing lengths" '(11 22) (map + (list 1 2 3) (list 10 20)))
+(test "map over three lists of differing lengths" '((1 10 a) (2 20 b))
+  (map list (list 1 2 3) (list 10 20 30) (list 'a 'b)))
+
+(def letters (list 'a 'b 'c))
+(list-set! letters 0 'x)
+(test "list-set! the first element" '(x b c) letters)
+(list-set! letters 1 'y)
+(test "list-set! a middle element" '(x y c) letters)
+(list-set! letters 2 'z)
+(test "list-set! the last element" '(x y z) letters)
+(test "list-set! past the end" #t (assert-error (lambda () (list-set! letters 3 'w)) "out of range"))
+(test "list-set! before the start" #t (assert-error (lambda () (list-set! letters (negate 1) 'w)) "out of range"))
+
+(def plist (list 'a 1 'b 2))
+(test "plist-get a present key" 2 (plist-get plist 'b))
+(test "plist-get an absent key" '() (plist-get plist 'z))
+(test "plist-put a new key" '(a 1 b 2 c 3) (plist-put plist 'c 3))
+(test "plist-put an existing key" '(a 9 b 2) (plist-put plist 'a 9))
+(test "plist-put returns a new list" '(a 1 b 2) plist)
+
+(def front (list 1 2))
+(def back (list 3))
+(def joined (append! front back (list 4)))
+(test "append! concatenates" '(1 2 3 4) joined)
+(test "append! reuses the first list's cells" #t (eq? joined front))
+(test "append! mutates the first list" '(1 2 3 4) front)
+(test "append! shares the later lists" #t (eq? (cdr (cdr joined)) back))
+(test "append! skips empty lists" '(1) (append! (list) (list 1)))
+(test "unfold builds a range" '(1 4 9 16)
+  (unfold (lambda (n) (eqv? n 5)) (lambda (n) (* n n)) (lambda (n) (+ n 1)) 1))
+(test "unfold stops immediately" '()
+  (unfold (lambda (n) #t) (lambda (n) n) (lambda (n) (+ n 1)) 0))
+(test "list-max" 9 (list-max (list 3 9 2)))
+(test "list-min" 2 (list-min (list 3 9 2)))
+(test "list-max with a key" (negate 5) (list-max (list 1 (negate 5) 2) (lambda (x) (* x x))))
+(test "list-min with a key" 1 (list-min (list 1 (negate 5) 2) (lambda (x) (* x x))))
+(test "list-max of an empty list raises" #t (assert-error (lambda () (list-max (list))) "at least one"))
+(test "list-min of an empty list raises" #t (assert-error (lambda () (list-min (list))) "at least one"))
+(test "max and min across arguments" '(5 2.5) (list (max 1 5 2) (min 4 2.5)))
+(test "apply spreads a list" 6 (apply + (list 1 2 3)))
+(test "list-index finds the first match" 1 (list-index (lambda (x) (<= 2 x)) (list 1 2 3)))
+(test "list-index without a match" '() (list-index (lambda (x) (eqv? x 9)) (list 1 2 3)))
+(test "find returns the first match" 2 (find (lambda (x) (<= 2 x)) (list 1 2 3)))
+(test "find without a match" '() (find (lambda (x) (eqv? x 9)) (list 1 2 3)))
+(test "find on an empty list" '() (find (lambda (x) #t) (list)))
+(def split (lambda (f pred items) (let-values (((prefix rest) (f pred items))) (list prefix rest))))
+(test "span with an empty prefix" '(() (3 1)) (split span (lambda (x) (<= x 2)) (list 3 1)))
+(test "span over the whole list" '((1 2) ()) (split span (lambda (x) (<= x 2)) (list 1 2)))
+(test "span splits at the first failure" '((1 2) (3 1)) (split span (lambda (x) (<= x 2)) (list 1 2 3 1)))
+(test "break with an empty prefix" '(() (3 1)) (split break (lambda (x) (<= 3 x)) (list 3 1)))
+(test "break over the whole list" '((1 2) ()) (split break (lambda (x) (<= 3 x)) (list 1 2)))
+(test "break splits at the first match" '((1 2) (3 1)) (split break (lambda (x) (<= 3 x)) (list 1 2 3 1)))
+(def parity (lambda (x) (let-values (((q r) (floor/ x 2))) (if (eqv? r 0) 'even 'odd))))
+(test "group-by parity keeps first-seen key order" '((odd 1 3 5) (even 2 4))
+  (group-by parity (list 1 2 3 4 5)))
+(test "group-by on an empty list" '() (group-by parity (list)))
+(test "merge interleaves sorted lists" '(1 2 3 4 5 6) (merge (list 1 3 5) (list 2 4 6) <=))
+(test "merge with an empty first list" '(1) (merge (list) (list 1) <=))
+(test "merge with an empty second list" '(1 2) (merge (list 1 2) (list) <=))
+(test "merge keeps the first list's element on a tie" '((1 . a) (1 . b))
+  (merge (list (cons 1 'a)) (list (cons 1 'b)) (lambda (x y) (if (<= (car y) (car x)) #f #t))))
+(test "last returns the final element" 3 (last (list 1 2 3)))
+(test "last of an empty list raises" #t (assert-error (lambda () (last (list))) "non-empty list"))
+(test "last-pair returns the final pair" '(3) (last-pair (list 1 2 3)))
+(test "last-pair of an improper list" (cons 1 2) (last-pair (cons 1 2)))
+(test "butlast drops the final element" '(1 2) (butlast (list 1 2 3)))
+(test "butlast of a single element" '() (butlast (list 1)))
+(test "butlast of an empty list" '() (butlast (list)))
+(def forwards (list 1 2 3))
+(test "reverse returns a reversed copy" '(3 2 1) (reverse forwards))
+(test "reverse leaves its argument alone" '(1 2 3) forwards)
+(test "reverse of an empty list" '() (reverse (list)))
+(def reused (list 1 2 3))
+(def backwards (reverse! reused))
+(test "reverse! reverses" '(3 2 1) backwards)
+(test "reverse! reuses the original cells" #t (eq? reused (last-pair backwards)))
+(test "reverse! of an empty list" '() (reverse! (list)))
+(test "sort-by orders by key" '(3 2 1) (sort-by negate (list 1 3 2)))
+(test "sort-by is stable" '((1 . a) (0 . b) (1 . c)) (sort-by (lambda (x) 0) (list (cons 1 'a) (cons 0 'b) (cons 1 'c))))
+(def key-calls (make-hash))
+(def counted-key (lambda (x) (hash-update! key-calls 'count (lambda (n) (+ n 1)) 0) (negate x)))
+(test "sort-by sorts a long list" (reverse (iota 200)) (sort-by counted-key (shuffle (iota 200))))
+(test "sort-by calls the key once per element" 200 (hash-ref key-calls 'count))
+(test "map keeps the input order" '(2 4 6 8) (map (lambda (x) (* x 2)) (list 1 2 3 4)))
+(test "filter keeps the input order" '(3 1 4) (filter (lambda (x) (<= x 4)) (list 3 9 1 4 5)))
+(test "map over several lists stops at the shortest" '(11 22) (map + (list 1 2 3) (list 10 20)))
+(test "map over an empty list" '() (map (lambda (x) x) (list)))
+(test "filter keeps the order of a long list" (iota 500 0 2)
+  (filter (lambda (x) (let-values (((q r) (floor/ x 2))) (eqv? r 0))) (iota 1000)))
+(test "map keeps the order of a long list" (iota 1000 1) (map (lambda (x) (+ x 1)) (iota 1000)))
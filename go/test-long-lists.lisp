@@ -0,0 +1,8 @@
+(def million (iota 1000000))
+
+(test "map over a million elements" 999999 (last (map (lambda (n) n) million)))
+(test "filter over a million elements" 500000 (car (filter (lambda (n) (<= 500000 n)) million)))
+(test "fold over a million elements" 499999500000 (fold + 0 million))
+(def seen (make-hash))
+(for-each (lambda (n) (hash-update! seen 'count (lambda (c) (+ c 1)) 0)) million)
+(test "for-each over a million elements" 1000000 (hash-ref seen 'count))
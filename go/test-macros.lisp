@@ -0,0 +1,36 @@
+(defmacro twice (x) (list 'begin x x))
+(defmacro quad (x) (list 'twice (list 'twice x)))
+(test "macroexpand-1 expands once" '(twice (twice y)) (macroexpand-1 '(quad y)))
+(test "macroexpand expands until the head is not a macro" '(begin (twice y) (twice y)) (macroexpand '(quad y)))
+(test "macroexpand-1 leaves a non-macro form alone" '(+ 1 2) (macroexpand-1 '(+ 1 2)))
+(test "macroexpand leaves a non-macro form alone" '(+ 1 2) (macroexpand '(+ 1 2)))
+
+(defmacro literal (x) (if (number? x) x (syntax-error "literal: expected a number" x)))
+(test "a macro that accepts its input expands normally" 1 (literal 1))
+(test "syntax-error raises at expansion time, before the argument runs" #t
+  (assert-error (lambda () (literal (error "evaluated"))) "Syntax error: literal: expected a number"))
+(test "syntax-error is raised only when the form is expanded" 'ok
+  (begin (lambda () (literal "never expanded")) 'ok))
+
+(test "a let-syntax macro expands in its body" 8
+  (let-syntax ((double (x) (list '* 2 x))) (double 4)))
+(test "a let-syntax macro is out of scope after the body" #t
+  (begin
+    (let-syntax ((double (x) (list '* 2 x))) (double 4))
+    (assert-error (lambda () (double 4)) "Undefined value :double")))
+(test "a let-syntax macro shadows a global macro of the same name" 'local
+  (let-syntax ((twice (x) (list 'quote 'local))) (twice 1)))
+(test "the global macro is back after the body" '(begin 1 1) (macroexpand-1 '(twice 1)))
+
+(defmacro my-first (x) (list 'car x))
+(test "expansions are not hygienic: a use-site binding captures a free name" '(2)
+  (let ((car cdr)) (my-first (list 1 2))))
+(defmacro capturing-or (a b) (list 'let (list (list 'tmp a)) (list 'if 'tmp 'tmp b)))
+(test "expansions are not hygienic: an introduced binder captures a user variable" #f
+  (let ((tmp 5)) (capturing-or #f tmp)))
+(defmacro gensym-or (a b)
+  (let ((tmp (gensym)))
+    (list 'let (list (list tmp a)) (list 'if tmp tmp b))))
+(test "a gensym binder avoids capturing a user variable" 5
+  (let ((tmp 5)) (gensym-or #f tmp)))
+(test "gensym symbols are distinct" #f (eq? (gensym) (gensym)))
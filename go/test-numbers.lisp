@@ -0,0 +1,83 @@
+(test "abs of a negative integer" 3 (abs (negate 3)))
+(test "abs of zero" 0 (abs 0))
+(test "abs of a positive integer" 3 (abs 3))
+(test "abs of a negative float" 2.5 (abs (negate 2.5)))
+(test "abs of a float zero" 0.0 (abs 0.0))
+(test "abs of a positive float" 2.5 (abs 2.5))
+
+(test "sign of a negative integer" (negate 1) (sign (negate 3)))
+(test "sign of zero" 0 (sign 0))
+(test "sign of a positive integer" 1 (sign 3))
+(test "sign of a negative float" (negate 1) (sign (negate 2.5)))
+(test "sign of a float zero" 0 (sign 0.0))
+(test "sign of a positive float" 1 (sign 2.5))
+
+(test "negate a negative integer" 3 (negate (negate 3)))
+(test "negate zero" 0 (negate 0))
+(test "negate a positive integer" (- 0 3) (negate 3))
+(test "negate a negative float" 2.5 (negate (negate 2.5)))
+(test "negate a positive float" (- 0.0 2.5) (negate 2.5))
+(test "abs rejects a non-number" #t (assert-error (lambda () (abs "x")) "expected number"))
+
+(def isqrt (lambda (n) (let-values (((s r) (exact-integer-sqrt n))) (list s r))))
+(test "exact-integer-sqrt of a perfect square" '(4 0) (isqrt 16))
+(test "exact-integer-sqrt of a non-square" '(4 1) (isqrt 17))
+(test "exact-integer-sqrt of zero" '(0 0) (isqrt 0))
+(test "exact-integer-sqrt of a large perfect square" '(1000000000 0) (isqrt 1000000000000000000))
+(test "exact-integer-sqrt of a large non-square" '(999999999 1999999998) (isqrt 999999999999999999))
+(test "exact-integer-sqrt rejects negatives" #t (assert-error (lambda () (isqrt (negate 1))) "non-negative"))
+(test "integer-log of a power" 3 (integer-log 8 2))
+(test "integer-log rounds down" 2 (integer-log 999 10))
+(test "exact? on an integer" #t (exact? 1))
+(test "exact? on a float" #f (exact? 1.5))
+(test "inexact? on a whole float" #t (inexact? 2.0))
+(test "integer? on a whole float" #t (integer? 2.0))
+(test "integer? on a fractional float" #f (integer? 2.5))
+(test "integer? on a non-number" #f (integer? "2"))
+(test "rational? on a float" #t (rational? 1.5))
+(test "real? on an integer" #t (real? 1))
+(test "exact? rejects non-numbers" #t (assert-error (lambda () (exact? 'a)) "expected number"))
+(test "approx=? within the default tolerance" #t (approx=? 0.3 (+ 0.1 0.2)))
+(test "approx=? outside the default tolerance" #f (approx=? 1.0 1.0001))
+(test "approx=? within an explicit tolerance" #t (approx=? 1.0 1.05 0.1))
+(test "approx=? outside an explicit tolerance" #f (approx=? 1.0 1.2 0.1))
+(test "approx=? mixes integers and floats" #t (approx=? 1 1.0))
+(test "assert-approx within tolerance" #t (assert-approx 0.3 (+ 0.1 0.2)))
+(test "assert-approx outside tolerance raises" #t
+  (assert-error (lambda () (assert-approx 1.0 1.5)) "assert-approx: expected 1.0, got 1.5"))
+(test "assert-approx with an explicit tolerance" #t (assert-approx 1.0 1.5 1.0))
+(test "string->number parses an integer" 42 (string->number "42"))
+(test "string->number parses a negative integer" (negate 7) (string->number "-7"))
+(test "string->number parses a float" 2.5 (string->number "2.5"))
+(test "string->number with a radix" 255 (string->number "ff" 16))
+(test "string->number rejects garbage" #f (string->number "abc"))
+(test "string->number rejects the empty string" #f (string->number ""))
+(test "string-number? accepts exponent notation" #t (string-number? "1e3"))
+(test "string-number? rejects garbage" #f (string-number? "x"))
+(def round-trips (lambda (x) (eqv? x (string->number (number->string x)))))
+(test "number->string uses the shortest form" "0.1" (number->string 0.1))
+(test "number->string keeps every digit that matters" "0.30000000000000004" (number->string (+ 0.1 0.2)))
+(test "number->string marks a whole float as inexact" "1.0" (number->string 1.0))
+(test "number->string with a radix" "ff" (number->string 255 16))
+(test "a sum round-trips bit for bit" #t (round-trips (+ 0.1 0.2)))
+(test "a large float round-trips bit for bit" #t (round-trips (string->number "1e21")))
+(test "the smallest subnormal round-trips bit for bit" #t (round-trips (string->number "5e-324")))
+(test "negative zero round-trips bit for bit" #t (round-trips (negate 0.0)))
+(test "+ reports a bad first operand" #t
+  (assert-error (lambda () (+ "a" 1)) "+: expected number in position 1, got string \"a\""))
+(test "+ reports a bad last operand" #t
+  (assert-error (lambda () (+ 1 2 "a")) "+: expected number in position 3, got string \"a\""))
+(test "- reports a bad operand" #t (assert-error (lambda () (- 1 'x)) "-: expected number in position 2, got symbol x"))
+(test "* reports a bad operand" #t (assert-error (lambda () (* #t 2)) "*: expected number in position 1, got boolean"))
+(test "<= reports a bad operand" #t (assert-error (lambda () (<= 1 (list 2))) "<=: expected number in position 2, got pair (2)"))
+(test "negate reports a bad operand" #t (assert-error (lambda () (negate "1")) "negate: expected number in position 1"))
+(test "floor/ requires integers" #t (assert-error (lambda () (floor/ 7 2.5)) "floor/: expected integer in position 2, got float 2.5"))
+(def show (lambda (x) (let ((port (open-output-string))) (display x port) (get-output-string port))))
+(test "set-float-precision! returns the previous setting" (negate 1) (set-float-precision! 3))
+(test "floats print with the set number of significant digits" "3.14" (show 3.14159))
+(test "a whole float still prints as inexact" "2.0" (show 2.0))
+(test "large floats switch to exponent notation" "1.23e+06" (show 1234567.0))
+(test "number->string ignores the print precision" "3.14159" (number->string 3.14159))
+(test "set-float-precision! rejects zero" #t (assert-error (lambda () (set-float-precision! 0)) "Expected positive precision"))
+(test "set-float-precision! restores the shortest form" 3 (set-float-precision! (negate 1)))
+(test "floats print exactly again" "3.14159" (show 3.14159))
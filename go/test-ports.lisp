@@ -0,0 +1,24 @@
+(def in (open-input-string "ab\ncd"))
+(test "peek-char does not consume" #\a (peek-char in))
+(test "read-char consumes a character" #\a (read-char in))
+(test "read-line reads the rest of the line" "b" (read-line in))
+(test "read-line reads a final line without a newline" "cd" (read-line in))
+(test "read-line at the end returns eof" #t (eof-object? (read-line in)))
+(test "read-char at the end returns eof" #t (eof-object? (read-char in)))
+(test "peek-char at the end returns eof" #t (eof-object? (peek-char in)))
+(test "an empty input port is at eof" #t (eof-object? (read-char (open-input-string ""))))
+
+(def out (open-output-string))
+(test "a new output port is empty" "" (get-output-string out))
+(write-char #\a out)
+(display "bc" out)
+(write "d" out)
+(display 1 out)
+(test "output ports collect everything written" "abc\"d\"1" (get-output-string out))
+(test "get-output-string does not reset the port" "abc\"d\"1" (get-output-string out))
+
+(def ready-port (open-input-string "x"))
+(test "a string port is always ready" #t (char-ready? ready-port))
+(test "read-char-no-hang reads from a ready port" #\x (read-char-no-hang ready-port))
+(test "read-char-no-hang at the end returns eof" #t (eof-object? (read-char-no-hang ready-port)))
+(test "char-ready? at the end of a string port" #t (char-ready? ready-port))
@@ -0,0 +1,14 @@
+(def inc (lambda (x) (+ x 1)))
+(def double (lambda (x) (* x 2)))
+(def minus3 (lambda (x) (- x 3)))
+
+(test "identity returns its argument" 'a (identity 'a))
+(test "composing nothing is identity" 5 ((compose) 5))
+(test "compose two functions right to left" 11 ((compose inc double) 5))
+(test "compose three functions right to left" 5 ((compose inc double minus3) 5))
+(test "composition order matters" 12 ((compose double inc) 5))
+
+(test "partial applies a builtin" 15 ((partial + 10) 5))
+(test "partial applies a user lambda" '(1 2 3) ((partial (lambda (a b c) (list a b c)) 1 2) 3))
+(test "partial works with map" '(2 4 6) (map (partial * 2) (list 1 2 3)))
+(test "partial with no arguments" 7 ((partial inc) 6))
@@ -0,0 +1,27 @@
+(def draws (make-hash))
+(dotimes (i 200)
+  (let ((n (random 5)))
+    (hash-update! draws (and (<= 0 n) (<= n 4)) (lambda (count) (+ count 1)) 0)))
+(test "random n stays within 0 to n-1" '(#t) (hash-keys draws))
+
+(def fraction (random))
+(test "random with no argument is a float in [0, 1)" #t (and (<= 0.0 fraction) (<= fraction 0.999999999)))
+(test "random rejects a non-positive bound" #t (assert-error (lambda () (random 0)) "positive"))
+
+(random-seed 42)
+(def first-run (list (random 1000) (random 1000) (random 1000)))
+(random-seed 42)
+(test "random-seed makes draws repeatable" first-run (list (random 1000) (random 1000) (random 1000)))
+
+(random-seed 7)
+(def shuffled (shuffle (iota 10)))
+(random-seed 7)
+(test "shuffle is repeatable with a fixed seed" shuffled (shuffle (iota 10)))
+(test "shuffle keeps every element" (iota 10) (sort-by identity shuffled))
+(random-seed 7)
+(def sampled (sample (iota 10) 3))
+(random-seed 7)
+(test "sample is repeatable with a fixed seed" sampled (sample (iota 10) 3))
+(test "sample takes k distinct elements" 3 (hash-table-count (let ((seen (make-hash))) (for-each (lambda (x) (hash-set! seen x #t)) sampled) seen)))
+(test "sample of the whole list" (iota 4) (sort-by identity (sample (iota 4) 4)))
+(test "sample with k too large raises" #t (assert-error (lambda () (sample (iota 3) 4)) "out of range"))
@@ -0,0 +1,10 @@
+(def integers-from (lambda (n) (cons-stream n (integers-from (+ n 1)))))
+(def even (lambda (n) (let-values (((q r) (floor/ n 2))) (eqv? r 0))))
+
+(test "stream-take on an infinite stream" '(0 1 2) (stream-take (integers-from 0) 3))
+(test "stream-map over an infinite stream" '(0 1 4 9)
+  (stream-take (stream-map (lambda (n) (* n n)) (integers-from 0)) 4))
+(test "stream-filter over an infinite stream" '(0 2 4 6)
+  (stream-take (stream-filter even (integers-from 0)) 4))
+(test "stream-map of stream-filter" '(1 3 5)
+  (stream-take (stream-map (lambda (n) (+ n 1)) (stream-filter even (integers-from 0))) 3))
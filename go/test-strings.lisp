@@ -0,0 +1,52 @@
+(test "~Nd right-justifies integers" "[   42]" (format "[~5d]" 42))
+(test "~Nd leaves wide integers alone" "[123456]" (format "[~3d]" 123456))
+(test "~Na pads on the right" "[ab   ]" (format "[~5a]" "ab"))
+(test "~Ns pads on the right" "[\"ab\" ]" (format "[~5s]" "ab"))
+(test "~,Nf fixes the decimals" "3.14" (format "~,2f" 3.14159))
+(test "~,Nf accepts integers" "2.000" (format "~,3f" 2))
+(test "~N,Nf right-justifies floats" "[  3.1]" (format "[~5,1f]" 3.14159))
+(test "columns line up in a table" "apple     3\npear     12\n"
+  (format "~8a~3d~%~8a~3d~%" "apple" 3 "pear" 12))
+
+(test "string-map uppercases" "ABC" (string-map char-upcase "abc"))
+(test "string-map of an empty string" "" (string-map char-upcase ""))
+(test "string-map works on runes" "ΛX" (string-map char-upcase "λx"))
+(def char-counts (make-hash))
+(string-for-each (lambda (c) (hash-update! char-counts c (lambda (n) (+ n 1)) 0)) "abca")
+(test "string-for-each visits every character" '(2 1 1)
+  (list (hash-ref char-counts #\a) (hash-ref char-counts #\b) (hash-ref char-counts #\c)))
+(test "string-for-each returns void" #t (void? (string-for-each (lambda (c) c) "x")))
+(test "string-regex-match returns the matched text" "123" (string-regex-match "[0-9]+" "abc123def"))
+(test "string-regex-match with capture groups" '("2024-06" "2024" "06")
+  (string-regex-match "([0-9]+)-([0-9]+)" "on 2024-06"))
+(test "string-regex-match with an unmatched optional group" (list "a" "a" (list))
+  (string-regex-match "(a)(b)?" "a"))
+(test "string-regex-match without a match" '() (string-regex-match "z" "abc"))
+(test "string-regex-replace with backreferences" "06/2024"
+  (string-regex-replace "([0-9]+)-([0-9]+)" "2024-06" "$2/$1"))
+(test "string-regex-replace replaces every match" "a_b_c" (string-regex-replace "-" "a-b-c" "_"))
+(test "an invalid regex raises" #t (assert-error (lambda () (string-regex-match "(" "x")) "Invalid regex"))
+(test "char->digit in base 10" 7 (char->digit #\7))
+(test "char->digit in base 16" 15 (char->digit #\f 16))
+(test "char->digit in base 16 ignores case" 15 (char->digit #\F 16))
+(test "char->digit of a non-digit" '() (char->digit #\x))
+(test "char->digit out of range for the base" '() (char->digit #\9 8))
+(test "digit->char in base 10" #\7 (digit->char 7))
+(test "digit->char in base 16" #\b (digit->char 11 16))
+(test "digit->char out of range for the base" '() (digit->char 12))
+(test "char-foldcase lowers ASCII" #\a (char-foldcase #\A))
+(test "char-foldcase lowers Greek" #\σ (char-foldcase #\Σ))
+(test "char-foldcase leaves ß alone" #\ß (char-foldcase #\ß))
+(test "string-foldcase expands ß" "strasse" (string-foldcase "Straße"))
+(test "folded strings compare equal across ß and SS" #t
+  (equal? (string-foldcase "STRASSE") (string-foldcase "straße")))
+(test "string-foldcase folds every sigma the same way" "σασ" (string-foldcase "ΣΑΣ"))
+(test "string-split on a separator keeps empty fields" '("a" "b" "" "c") (string-split "a,b,,c" ","))
+(test "string-split with a limit" '("a" "b,c") (string-split "a,b,c" "," 2))
+(test "string-split with a negative limit splits everywhere" '("a" "b" "c") (string-split "a,b,c" "," (negate 1)))
+(test "string-split rejects a zero limit" #t (assert-error (lambda () (string-split "a" "," 0)) "non-zero"))
+(test "string-split treats the separator literally by default" '("a1b22c") (string-split "a1b22c" "[0-9]+"))
+(test "string-split with a regex separator" '("a" "b" "c") (string-split "a1b22c" "[0-9]+" (negate 1) #t))
+(test "string-split with a regex separator and a limit" '("a" "b22c") (string-split "a1b22c" "[0-9]+" 2 #t))
+(test "string-split rejects an invalid regex" #t
+  (assert-error (lambda () (string-split "a" "(" (negate 1) #t)) "Invalid regex"))
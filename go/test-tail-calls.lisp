@@ -0,0 +1,38 @@
+(def n 1000000)
+
+(def if-even? (lambda (n) (if (eqv? n 0) #t (if-odd? (- n 1)))))
+(def if-odd? (lambda (n) (if (eqv? n 0) #f (if-even? (- n 1)))))
+(test "if" #t (if-even? n))
+
+(def cond-even? (lambda (n) (cond ((eqv? n 0) #t) (else (cond-odd? (- n 1))))))
+(def cond-odd? (lambda (n) (cond ((eqv? n 0) #f) (else (cond-even? (- n 1))))))
+(test "cond" #t (cond-even? n))
+
+(def when-even? (lambda (n) (if (eqv? n 0) #t (when #t (when-odd? (- n 1))))))
+(def when-odd? (lambda (n) (if (eqv? n 0) #f (when #t (when-even? (- n 1))))))
+(test "when" #t (when-even? n))
+
+(def unless-even? (lambda (n) (if (eqv? n 0) #t (unless #f (unless-odd? (- n 1))))))
+(def unless-odd? (lambda (n) (if (eqv? n 0) #f (unless #f (unless-even? (- n 1))))))
+(test "unless" #t (unless-even? n))
+
+(def begin-even? (lambda (n) (if (eqv? n 0) #t (begin n (begin-odd? (- n 1))))))
+(def begin-odd? (lambda (n) (if (eqv? n 0) #f (begin n (begin-even? (- n 1))))))
+(test "begin" #t (begin-even? n))
+
+(def let-even? (lambda (n) (if (eqv? n 0) #t (let ((m (- n 1))) (let-odd? m)))))
+(def let-odd? (lambda (n) (if (eqv? n 0) #f (let ((m (- n 1))) (let-even? m)))))
+(test "let" #t (let-even? n))
+
+(def and-even? (lambda (n) (if (eqv? n 0) #t (and #t (and-odd? (- n 1))))))
+(def and-odd? (lambda (n) (if (eqv? n 0) #f (and #t (and-even? (- n 1))))))
+(test "and" #t (and-even? n))
+
+(def or-even? (lambda (n) (if (eqv? n 0) #t (or #f (or-odd? (- n 1))))))
+(def or-odd? (lambda (n) (if (eqv? n 0) #f (or #f (or-even? (- n 1))))))
+(test "or" #t (or-even? n))
+
+(def case-even? (lambda (n) (case n ((0) #t) (else (case-odd? (- n 1))))))
+(def case-odd? (lambda (n) (case n ((0) #f) (else (case-even? (- n 1))))))
+(test "case" #t (case-even? n))
+
@@ -0,0 +1,16 @@
+(def now (current-time))
+(test "current-time is after 2020" #t (<= 1577836800 now))
+(test "current-time is before 2100" #t (<= now 4102444800))
+(test "time->string formats the epoch as RFC 3339" "1970-01-01T00:00:00Z" (time->string 0))
+(test "time->string output for now is RFC 3339" (time->string now)
+  (string-regex-match "^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}Z$" (time->string now)))
+(test "time->string with a layout" "2009-02-13" (time->string 1234567890 "2006-01-02"))
+
+(def spin (lambda (n) (if (eqv? n 0) 'done (spin (- n 1)))))
+(def forever (lambda () (forever)))
+(test "with-timeout returns the body's value when it completes" 'done (with-timeout 5 'expired (spin 1000)))
+(test "with-timeout returns the fallback when the body runs too long" 'expired (with-timeout 0.05 'expired (forever)))
+(test "an inner timeout expiring leaves the outer one running" '(inner done)
+  (with-timeout 5 'outer (list (with-timeout 0.05 'inner (forever)) (spin 10))))
+(test "with-timeout passes other errors through" #t
+  (assert-error (lambda () (with-timeout 5 'expired (error "boom"))) "boom"))
@@ -0,0 +1,33 @@
+(def fails (lambda (thunk) (try (begin (thunk) 'returned) (catch e 'error))))
+
+(test "(+)" 0 (+))
+(test "(+ 5)" 5 (+ 5))
+(test "(+ 1 2 3)" 6 (+ 1 2 3))
+
+(test "(*)" 1 (*))
+(test "(* 5)" 5 (* 5))
+(test "(* 2 3 4)" 24 (* 2 3 4))
+
+(test "(min)" 'error (fails (lambda () (min))))
+(test "(min 5)" 5 (min 5))
+(test "(min 3 1 2)" 1 (min 3 1 2))
+
+(test "(max)" 'error (fails (lambda () (max))))
+(test "(max 5)" 5 (max 5))
+(test "(max 3 1 2)" 3 (max 3 1 2))
+
+(test "(and)" #t (and))
+(test "(and 5)" 5 (and 5))
+(test "(and 1 2 3)" 3 (and 1 2 3))
+
+(test "(or)" #f (or))
+(test "(or 5)" 5 (or 5))
+(test "(or #f #f 3)" 3 (or #f #f 3))
+
+(test "(string-append)" "" (string-append))
+(test "(string-append \"a\")" "a" (string-append "a"))
+(test "(string-append \"a\" \"b\" \"c\")" "abc" (string-append "a" "b" "c"))
+
+(test "(append)" '() (append))
+(test "(append (list 1))" '(1) (append (list 1)))
+(test "(append (list 1) (list 2) (list 3))" '(1 2 3) (append (list 1) (list 2) (list 3)))
@@ -0,0 +1,22 @@
+(test "make-vector fills with 0 by default" (vector 0 0 0) (make-vector 3))
+(test "make-vector uses an explicit fill" (vector 'x 'x) (make-vector 2 'x))
+(test "make-vector of length zero is empty" 0 (vector-length (make-vector 0)))
+(test "make-vector rejects a negative length" #t
+  (assert-error (lambda () (make-vector (negate 1))) "non-negative"))
+
+(def unsorted (vector 3 1 2))
+(vector-sort! unsorted <=)
+(test "vector-sort! sorts in place" (vector 1 2 3) unsorted)
+(test "vector-sort! with a descending comparator" (vector 3 2 1)
+  (vector-sort! (vector 1 3 2) (lambda (a b) (<= b a))))
+(test "vector-sort! on an empty vector" (vector) (vector-sort! (vector) <=))
+(test "vector-sort! on a single element" (vector 5) (vector-sort! (vector 5) <=))
+
+(test "#( reads a vector" '#(1 2 3) (assert-type '#(1 2 3) 'vector))
+(test "a vector literal holds its elements" 2 (vector-ref '#(1 2 3) 1))
+(test "a vector literal evaluates to itself" (vector 1 "two" #\3) #(1 "two" #\3))
+(test "an empty vector literal" 0 (vector-length #()))
+(test "vector literals nest" (vector 'a (list 'b (vector 'c))) '#(a (b #(c))))
+(test "vector literal elements are not evaluated" 'x (vector-ref #(x) 0))
+(test "a paren character literal is not a vector" #\( (vector-ref #(#\() 0))
+(test "a vector prints as a literal" "#(1 (2) #(3))" (format "~s" (vector 1 (list 2) (vector 3))))
@@ -0,0 +1,18 @@
+(def path (string-append (or (getenv "TMPDIR") "/tmp") "/word-frequency.txt"))
+(write-file path "the quick brown fox
+jumps over the lazy dog
+the dog sleeps
+")
+
+(def counts (make-hash 'ordered))
+(for-each
+  (lambda (word) (hash-update! counts word (lambda (n) (+ n 1)) 0))
+  (string-split (read-file path)))
+
+(test "counts a word used three times" 3 (hash-ref counts "the"))
+(test "counts a word used twice" 2 (hash-ref counts "dog"))
+(test "counts a word used once" 1 (hash-ref counts "fox"))
+(test "an absent word falls back to the default" 0 (hash-ref counts "cat" 0))
+(test "counts each distinct word once" 9 (hash-table-count counts))
+(test "keeps words in first-seen order" '("the" "quick" "brown" "fox" "jumps" "over" "lazy" "dog" "sleeps")
+  (hash-keys counts))
@@ -0,0 +1,3 @@
+(test "this one passes" 4 (+ 2 2))
+(test "this one fails on purpose" 5 (+ 2 2))
+(test "errors count as failures" 1 (error "boom"))